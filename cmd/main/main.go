@@ -1,6 +1,9 @@
 package main
 
 import (
+	"fmt"
+	"net"
+	"os"
 	"sync"
 
 	"github.com/Timelessprod/algorep/pkg/client"
@@ -14,12 +17,45 @@ var wg sync.WaitGroup
 var logger *zap.Logger = core.Logger
 
 func main() {
+	// The workers execute the program again to set up the sandbox of a job
+	if worker.IsSandboxHelper() {
+		worker.RunSandboxHelper()
+	}
+
 	// To flush the last log in the buffer
 	defer logger.Sync()
 
+	// A one-shot command of the command line is run by the cluster serving the control socket
+	runRepl := len(os.Args) == 1
+	if !runRepl && os.Args[1] != client.SERVE_COMMAND {
+		exitCode := client.RunCommandLine(os.Args[1:])
+		logger.Sync()
+		os.Exit(exitCode)
+	}
+	// Only the cluster started by `job_scheduler serve` serves the command line, so that several
+	// clusters with a REPL can run in the same directory
+	var listener net.Listener
+	if !runRepl {
+		var err error
+		if listener, err = client.ListenCommandLine(); err != nil {
+			fmt.Println("Error: ", err)
+			os.Exit(client.EXIT_ERROR)
+		}
+	}
+
 	// Init the channel map
 	core.Config.NodeChannelMap = InitNodeChannelMap()
 
+	// Create the clients, which can all send requests concurrently: the client 0 runs the interactive
+	// console, the next ones run the commands of the command line, and the last one forwards the
+	// requests of the HTTP gateway. They are added to the channel map before the nodes are started.
+	replClient := client.NewClientNode()
+	commandLineClientList := make([]*client.ClientNode, 0, core.Config.CommandLineClientCount)
+	for i := uint32(0); i < core.Config.CommandLineClientCount; i++ {
+		commandLineClientList = append(commandLineClientList, client.NewClientNode())
+	}
+	gatewayClient := client.NewClientNode()
+
 	// Create schedulers and start them
 	for i := uint32(0); i < core.Config.SchedulerNodeCount; i++ {
 		wg.Add(1)
@@ -50,14 +86,23 @@ func main() {
 		go node.Run()
 	}
 
-	// Run interactive console
-	client := client.ClientNode{}
-	// We can image use several clients in different terminals
-	// Here, for simplicity, we use only one client
-	client.Init(0)
-	// Add client channels to the map
-	core.Config.NodeChannelMap[core.ClientNodeType] = append(core.Config.NodeChannelMap[core.ClientNodeType], &client.Channel)
-	go client.Run()
+	// Run the clients
+	if runRepl {
+		go replClient.Run()
+	}
+	if listener != nil {
+		for _, node := range commandLineClientList {
+			go node.ServeCommandLine(listener)
+		}
+	}
+	// The cluster runs without the gateway if its address is not available
+	if core.Config.GatewayAddress != "" {
+		if gatewayListener, err := client.ListenGateway(); err != nil {
+			fmt.Println("Warning: HTTP gateway disabled: ", err)
+		} else {
+			go client.NewGateway(gatewayClient).Serve(gatewayListener)
+		}
+	}
 
 	// Wait for all schedulers to finish before exiting the main function
 	// If we don't wait, the program will exit before the nodes have time to finish
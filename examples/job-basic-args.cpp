@@ -0,0 +1,18 @@
+#include <cstdlib>
+#include <iostream>
+#include <string>
+
+// Print the arguments, the GREETING environment variable and the standard input of the job
+int main(int argc, char **argv)
+{
+    for (int i = 1; i < argc; i++)
+        std::cout << "Argument " << i << " : " << argv[i] << std::endl;
+
+    const char *greeting = std::getenv("GREETING");
+    std::cout << "GREETING : " << (greeting ? greeting : "(unset)") << std::endl;
+
+    std::string line;
+    while (std::getline(std::cin, line))
+        std::cout << "Stdin : " << line << std::endl;
+    return 0;
+}
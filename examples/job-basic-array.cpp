@@ -0,0 +1,15 @@
+#include <iostream>
+#include <cstdlib>
+
+int main()
+{
+    const char *index = std::getenv("JOB_ARRAY_INDEX");
+    if (index == nullptr)
+    {
+        std::cerr << "This job must be submitted as a job array (SUBMIT --array)";
+        return 1;
+    }
+    long n = std::atol(index);
+    std::cout << "Square of " << n << " = " << n * n;
+    return 0;
+}
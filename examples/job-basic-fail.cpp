@@ -0,0 +1,7 @@
+#include <iostream>
+
+int main()
+{
+    std::cerr << "Something went wrong!";
+    return 1;
+}
@@ -0,0 +1,15 @@
+#include <iostream>
+#include <cstdlib>
+#include <ctime>
+
+int main()
+{
+    std::srand(std::time(nullptr));
+    if (std::rand() % 2 == 0)
+    {
+        std::cerr << "Unlucky, please retry!";
+        return 3;
+    }
+    std::cout << "Lucky!";
+    return 0;
+}
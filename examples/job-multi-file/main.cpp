@@ -0,0 +1,10 @@
+#include <cstdlib>
+#include <iostream>
+#include "primes.hpp"
+
+int main(int argc, char **argv)
+{
+    int n = argc > 1 ? std::atoi(argv[1]) : 100000;
+    std::cout << "There are " << count_primes(n) << " prime numbers lower than " << n << std::endl;
+    return 0;
+}
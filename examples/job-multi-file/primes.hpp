@@ -0,0 +1,4 @@
+#pragma once
+
+// Count the prime numbers lower than n
+int count_primes(int n);
@@ -14,16 +14,7 @@ echo "STATUS"
 sleep 2
 echo "STATUS 1-1"
 
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
+echo "WAIT ALL"
 echo "STATUS"
 
 sleep 2
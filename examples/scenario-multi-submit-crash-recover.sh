@@ -55,28 +55,7 @@ echo "RECOVER 3"
 sleep 2
 echo "STATUS"
 
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
+echo "WAIT ALL"
 echo "STATUS"
 
 sleep 1
@@ -49,22 +49,7 @@ echo "SUBMIT examples/job-basic-hello.cpp"
 sleep 2
 echo "STATUS"
 
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
+echo "WAIT ALL"
 echo "STATUS"
 
 sleep 1
@@ -21,22 +21,7 @@ echo "STATUS"
 sleep 2
 echo "STATUS 2-1"
 
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
-echo "STATUS"
-
-sleep 5
+echo "WAIT ALL"
 echo "STATUS"
 
 sleep 1
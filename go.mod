@@ -2,7 +2,11 @@ module github.com/Timelessprod/algorep
 
 go 1.18
 
-require go.uber.org/zap v1.23.0
+require (
+	go.uber.org/zap v1.23.0
+	golang.org/x/sys v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	go.uber.org/atomic v1.7.0 // indirect
@@ -0,0 +1,352 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/****************
+ ** Client API **
+ ****************/
+
+// The methods below let a Go program embedded in the process of the cluster submit and follow jobs
+// with a ClientNode, without the REPL which is built on top of them. The client must be created by
+// NewClientNode before the nodes are started, as in cmd/main, and ServeBlobs must run while it
+// submits jobs if the blob stores are enabled. The methods can be called concurrently: each request
+// has its own id, with which the response of the leader is routed to it. The context of each method
+// cancels it: it then returns the error of the context.
+
+// Ref is the reference of a job `<JobId>-<Term>`, of a job of a job array `<JobId>-<Term>_<Index>`,
+// or of a schedule `S<Id>-<Term>`
+type Ref = string
+
+// Errors returned by the client API
+var (
+	ErrClusterNotStarted = errors.New("Cluster is not started yet")
+	ErrNoLeader          = errors.New("No response from leader after several tries")
+	ErrJobNotFound       = errors.New("Job not found")
+)
+
+// RejectedError is returned when the leader rejects a request, for example a job depending on a
+// job which does not exist or the cancellation of a job which has already ended
+type RejectedError struct {
+	Message string
+	// The request has been rejected because its job does not exist
+	JobNotFound bool
+}
+
+// Error returns the message of the leader
+func (err *RejectedError) Error() string {
+	return err.Message
+}
+
+// Is lets errors.Is match ErrJobNotFound when the job of the request does not exist
+func (err *RejectedError) Is(target error) bool {
+	return target == ErrJobNotFound && err.JobNotFound
+}
+
+// JobEndedError is returned by Watch when the watched job had already ended when the watch started
+type JobEndedError struct {
+	Job core.Job
+}
+
+// Error describes the final state of the job
+func (err *JobEndedError) Error() string {
+	return "The job has already ended. " + formatJobResult(err.Job)
+}
+
+// JobSpec describes a job to submit: the job itself, built by LoadJobSpec from a job file or by the
+// caller, and when it is submitted
+type JobSpec struct {
+	Job core.Job
+	// Time at which the job is submitted by a schedule instead of now
+	StartAt time.Time
+	// Cron expression of a schedule submitting the job periodically instead of once
+	Cron string
+}
+
+// IsSchedule checks if the job is submitted by a schedule instead of now
+func (spec JobSpec) IsSchedule() bool {
+	return spec.Cron != "" || !spec.StartAt.IsZero()
+}
+
+// LoadJobSpec loads the specification of a job from a job file: a source file, a directory or a tar
+// archive of a multi-file job, or a job manifest (see JobManifest)
+func LoadJobSpec(path string) (JobSpec, error) {
+	job, err := loadJobFromFile(path, newJobOptions(), map[string]bool{})
+	return JobSpec{Job: job}, err
+}
+
+// JobList is a page of the jobs matching a query, listed by ListJobs
+type JobList struct {
+	Jobs []core.JobSummary
+	// Number of jobs matching the query
+	JobCount  uint32
+	Schedules map[Ref]core.Schedule
+}
+
+// WatchOptions selects the state changes of the jobs received by Watch
+type WatchOptions struct {
+	// Reference of the watched job, or of the watched job array and its jobs (all the jobs if empty)
+	JobReference Ref
+	// Labels which the watched jobs must have
+	Labels map[string]string
+	// If Replay is set, the state changes caused by the entries of the log after FromIndex are
+	// received first
+	Replay    bool
+	FromIndex uint32
+}
+
+// ServeBlobs sends the large inputs of the jobs submitted by the client to the workers which
+// request them. It returns immediately if the blob stores are disabled.
+func (client *ClientNode) ServeBlobs() {
+	if client.blobStore != nil {
+		client.blobStore.Serve(client.Channel.RequestBlob)
+	}
+}
+
+// checkResponse returns the error of a request rejected by the leader
+func checkResponse(response *core.ResponseCommandRPC) error {
+	if !response.Success {
+		return &RejectedError{Message: response.Message, JobNotFound: response.JobNotFound}
+	}
+	return nil
+}
+
+// Submit submits a job, or a schedule if the start time or the cron expression of the job is set,
+// and returns its reference.
+// The submission is at least once: if the context is cancelled, or if the leader does not answer in
+// time and the request is sent again, after the leader has received the request, the job may have
+// been added to the log anyway, possibly twice. An error therefore does not mean that the job has
+// not been submitted: the caller can check it with ListJobs, for example with a label set on the job.
+func (client *ClientNode) Submit(ctx context.Context, spec JobSpec) (Ref, error) {
+	if !isClusterStarted() {
+		return "", ErrClusterNotStarted
+	}
+	if spec.Cron != "" {
+		if _, err := core.ParseCronExpression(spec.Cron); err != nil {
+			return "", err
+		}
+	}
+	job := spec.Job
+	job.WorkerId = core.NO_WORKER
+	if err := job.StoreBlobs(client.blobStore, core.JobInputBlobFieldList); err != nil {
+		return "", err
+	}
+	entry := core.Entry{
+		Type: core.OpenJob,
+		Job:  job,
+	}
+	if spec.IsSchedule() {
+		entry = core.Entry{
+			Type:     core.AddSchedule,
+			Schedule: core.Schedule{Cron: spec.Cron, NextRun: spec.StartAt, Job: job},
+		}
+	}
+	request := core.RequestCommandRPC{
+		FromNode:    client.NodeCard,
+		CommandType: core.AppendEntryCommand,
+		Entries:     []core.Entry{entry},
+	}
+	response, err := client.sendMessageToLeader(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	if err := checkResponse(response); err != nil {
+		return "", err
+	}
+	if response.ScheduleReference != "" {
+		return response.ScheduleReference, nil
+	}
+	return response.JobReference, nil
+}
+
+// Unschedule removes a schedule
+func (client *ClientNode) Unschedule(ctx context.Context, reference Ref) error {
+	if !isClusterStarted() {
+		return ErrClusterNotStarted
+	}
+	schedule, err := parseScheduleReference(reference)
+	if err != nil {
+		return err
+	}
+	request := core.RequestCommandRPC{
+		FromNode:    client.NodeCard,
+		CommandType: core.AppendEntryCommand,
+		Entries:     []core.Entry{{Type: core.RemoveSchedule, Schedule: schedule}},
+	}
+	response, err := client.sendMessageToLeader(ctx, request)
+	if err != nil {
+		return err
+	}
+	return checkResponse(response)
+}
+
+// Cancel cancels a job, or all the jobs of a job array, which has not ended yet
+func (client *ClientNode) Cancel(ctx context.Context, reference Ref) error {
+	if !isClusterStarted() {
+		return ErrClusterNotStarted
+	}
+	job, err := core.ParseJobReference(reference)
+	if err != nil {
+		return err
+	}
+	request := core.RequestCommandRPC{
+		FromNode:    client.NodeCard,
+		CommandType: core.AppendEntryCommand,
+		Entries:     []core.Entry{{Type: core.CancelJob, Job: job}},
+	}
+	response, err := client.sendMessageToLeader(ctx, request)
+	if err != nil {
+		return err
+	}
+	return checkResponse(response)
+}
+
+// Status returns a job with all its fields, and the jobs of the job array if it is one indexed by
+// their references. The large inputs and outputs of the job may be in blob stores (see
+// core.Job.LoadBlobs).
+func (client *ClientNode) Status(ctx context.Context, reference Ref) (core.Job, map[Ref]core.Job, error) {
+	if !isClusterStarted() {
+		return core.Job{}, nil, ErrClusterNotStarted
+	}
+	request := core.RequestCommandRPC{
+		FromNode:            client.NodeCard,
+		CommandType:         core.StatusCommand,
+		DetailJobReferences: []string{reference},
+	}
+	response, err := client.sendMessageToLeader(ctx, request)
+	if err != nil {
+		return core.Job{}, nil, err
+	}
+	job, ok := response.JobMap[reference]
+	if !ok {
+		return core.Job{}, nil, ErrJobNotFound
+	}
+	delete(response.JobMap, reference)
+	return job, response.JobMap, nil
+}
+
+// ListJobs returns a page of the summaries of the jobs matching a query, and the schedules
+func (client *ClientNode) ListJobs(ctx context.Context, query core.JobQuery) (*JobList, error) {
+	if !isClusterStarted() {
+		return nil, ErrClusterNotStarted
+	}
+	request := core.RequestCommandRPC{
+		FromNode:    client.NodeCard,
+		CommandType: core.StatusCommand,
+		JobQuery:    query,
+	}
+	response, err := client.sendMessageToLeader(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &JobList{Jobs: response.JobSummaries, JobCount: response.JobCount, Schedules: response.ScheduleMap}, nil
+}
+
+// Wait blocks until the given jobs, or all the jobs if none is given, have ended or until the
+// timeout (no limit if 0) has passed, and returns the jobs and whether they have all ended. The
+// leader holds each request until the jobs have ended or for at most Config.WaitPollTimeout, so the
+// client only sends a new request when it has expired.
+func (client *ClientNode) Wait(ctx context.Context, referenceList []Ref, timeout time.Duration) (map[Ref]core.Job, bool, error) {
+	if !isClusterStarted() {
+		return nil, false, ErrClusterNotStarted
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		request := core.RequestCommandRPC{
+			FromNode:      client.NodeCard,
+			CommandType:   core.WaitCommand,
+			JobReferences: referenceList,
+		}
+		if timeout > 0 {
+			request.WaitTimeout = time.Until(deadline)
+		}
+		response, err := client.sendMessageToLeaderWithTimeout(ctx, request, core.Config.WaitPollTimeout+core.Config.MaxFindLeaderTimeout)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := checkResponse(response); err != nil {
+			return nil, false, err
+		}
+		if response.JobsEnded || (timeout > 0 && !time.Now().Before(deadline)) {
+			return response.JobMap, response.JobsEnded, nil
+		}
+	}
+}
+
+// Watch calls handleEvent for each state change of the watched jobs, as the leader applies them,
+// until the context is done or, if a single job is watched, until it ends. It returns a
+// JobEndedError if the watched job had already ended. The subscription is resumed from the last
+// event received when it is ended by the leader, for example after a change of leader.
+func (client *ClientNode) Watch(ctx context.Context, options WatchOptions, handleEvent func(core.JobEvent)) error {
+	if !isClusterStarted() {
+		return ErrClusterNotStarted
+	}
+	if options.JobReference != "" {
+		if _, err := core.ParseJobReference(options.JobReference); err != nil {
+			return err
+		}
+	}
+	subscription := core.JobSubscription{
+		JobReference: options.JobReference,
+		Labels:       options.Labels,
+		Replay:       options.Replay,
+		FromIndex:    options.FromIndex,
+		Done:         make(chan struct{}),
+	}
+	defer close(subscription.Done)
+
+	for {
+		subscription.Events = make(chan core.JobEvent, core.Config.ChannelBufferSize)
+		request := core.RequestCommandRPC{
+			FromNode:     client.NodeCard,
+			CommandType:  core.WatchCommand,
+			Subscription: subscription,
+		}
+		response, err := client.sendMessageToLeader(ctx, request)
+		if err != nil {
+			return err
+		}
+		if err := checkResponse(response); err != nil {
+			return err
+		}
+		if job, ok := response.JobMap[subscription.JobReference]; ok && !subscription.Replay && job.State.IsTerminal() {
+			return &JobEndedError{Job: job}
+		}
+		if !subscription.Replay {
+			subscription.Replay = true
+			subscription.FromIndex = response.AppliedIndex
+		}
+
+		for subscriptionEnded := false; !subscriptionEnded; {
+			select {
+			case event, ok := <-subscription.Events:
+				if !ok {
+					subscriptionEnded = true
+					break
+				}
+				subscription.FromIndex = event.Index
+				handleEvent(event)
+				if event.Job.GetReference() == subscription.JobReference && event.Job.State.IsTerminal() {
+					return nil
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// sleepContext waits for a duration, or until the context is done
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-time.After(duration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/***************
+ ** Artifacts **
+ ***************/
+
+// handleFetchCommand handles the fetch command to download the artifacts of a job, or of each job
+// of a job array in a subdirectory named after its reference
+func (client *ClientNode) handleFetchCommand(tokenList []string) {
+	if len(tokenList) != 3 {
+		client.printUsage(FETCH_COMMAND_USAGE)
+		return
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
+		return
+	}
+
+	reference, directory := tokenList[1], client.resolveCommandPath(tokenList[2])
+	fmt.Fprint(client.out, "Fetching artifacts... ")
+	job, jobMap, err := client.Status(context.Background(), reference)
+	if errors.Is(err, ErrJobNotFound) {
+		client.fail(INVALID_JOB_REFERENCE_MESSAGE)
+		return
+	} else if err != nil {
+		client.printError(err)
+		return
+	}
+
+	if !job.IsArray() {
+		fileCount, err := client.fetchArtifacts(job, directory)
+		if err != nil {
+			client.printError(err)
+			return
+		}
+		fmt.Fprintf(client.out, "Done. %d files written to %s\n", fileCount, directory)
+		return
+	}
+	totalFileCount := 0
+	for index := job.ArrayStart; index < job.ArrayStart+int(job.ArraySize); index++ {
+		childReference := job.GetArrayChildReference(index)
+		fileCount, err := client.fetchArtifacts(jobMap[childReference], filepath.Join(directory, childReference))
+		if err != nil {
+			client.fail("Error for job", childReference, ": ", err)
+			return
+		}
+		totalFileCount += fileCount
+	}
+	fmt.Fprintf(client.out, "Done. %d files written to %s\n", totalFileCount, directory)
+}
+
+// fetchArtifacts extracts the artifacts of a job into a directory and returns the number of files
+func (client *ClientNode) fetchArtifacts(job core.Job, directory string) (int, error) {
+	if !job.State.IsTerminal() {
+		return 0, fmt.Errorf("The job %s has not ended yet", job.GetReference())
+	}
+	if len(job.ArtifactList) == 0 {
+		return 0, nil
+	}
+	if err := job.LoadBlobs(client.blobStore, []core.BlobField{core.ArtifactsBlobField}); err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return 0, err
+	}
+	if err := core.ExtractArchive(job.Artifacts, directory); err != nil {
+		return 0, err
+	}
+	return len(job.ArtifactList), nil
+}
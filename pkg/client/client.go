@@ -2,10 +2,14 @@ package client
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Timelessprod/algorep/pkg/core"
@@ -21,17 +25,45 @@ func printPrompt() {
 	fmt.Print("\n[Chaos Monkey REPL] $ ")
 }
 
+// Set to 1 once the cluster has been started by one of the clients of the process
+var clusterIsStarted int32
+
+// isClusterStarted checks if the START command has been run
+func isClusterStarted() bool {
+	return atomic.LoadInt32(&clusterIsStarted) == 1
+}
+
 /*****************
  ** Client Node **
  *****************/
 
 type ClientNode struct {
-	Id               uint32
-	NodeCard         core.NodeCard
-	LastLeaderId     uint32
-	ClusterIsStarted bool
+	Id       uint32
+	NodeCard core.NodeCard
+	// Accessed atomically since the requests of the client can be sent concurrently
+	LastLeaderId uint32
+
+	// Id of the last request sent to a scheduler
+	lastRequestId uint64
+	// Channels of the requests waiting for their response, by request id (see routeResponses)
+	pendingRequestMutex sync.Mutex
+	pendingRequestMap   map[uint64]chan core.ResponseCommandRPC
 
 	Channel core.ChannelContainer
+	// Large inputs of the submitted jobs and outputs fetched from the workers, nil if the blob stores are disabled
+	blobStore *core.BlobStore
+
+	// Outputs of the commands, the standard error of the jobs being written to errOut
+	out    io.Writer
+	errOut io.Writer
+	// Exit code of the last command (see EXIT_SUCCESS)
+	exitCode int
+	// Directory of the relative paths given to the commands (the working directory of the process if empty)
+	workingDirectory string
+	// Stops the cluster once the STOP command has printed its message
+	stop func()
+	// Format of the results of the commands
+	outputFormat OutputFormat
 }
 
 // Init initializes the client node
@@ -42,197 +74,616 @@ func (client *ClientNode) Init(id uint32) {
 		RequestCommand:  make(chan core.RequestCommandRPC, core.Config.ChannelBufferSize),
 		ResponseCommand: make(chan core.ResponseCommandRPC, core.Config.ChannelBufferSize),
 	}
+	client.blobStore = core.NewBlobStore(client.NodeCard)
+	if client.blobStore != nil {
+		client.Channel.RequestBlob = make(chan core.BlobRequest, core.Config.ChannelBufferSize)
+	}
 	client.LastLeaderId = 0 // Valeur par défaut le temps de trouver le leader
-	client.ClusterIsStarted = false
+	client.pendingRequestMap = make(map[uint64]chan core.ResponseCommandRPC)
+	go client.routeResponses()
+	client.out = os.Stdout
+	client.errOut = os.Stderr
+	client.stop = func() { os.Exit(0) }
+	client.outputFormat = TABLE_FORMAT
 }
 
 // Run the client node
 func (client *ClientNode) Run() {
-	fmt.Println("Welcome to the Chaos Monkey REPL !")
-	fmt.Println("==================================")
-	fmt.Println(HELP_MESSAGE)
-	fmt.Println()
+	fmt.Fprintln(client.out, "Welcome to the Chaos Monkey REPL !")
+	fmt.Fprintln(client.out, "==================================")
+	fmt.Fprintln(client.out, HELP_MESSAGE)
+	fmt.Fprintln(client.out)
+	go client.ServeBlobs()
 
 	reader := bufio.NewScanner(os.Stdin)
 	printPrompt()
 	for reader.Scan() {
-		fmt.Print(">>> ")
+		fmt.Fprint(client.out, ">>> ")
 		client.handleCommand(reader.Text())
 		printPrompt()
 	}
 }
 
+// NewClientNode creates a client node and adds its channels to Config.NodeChannelMap. It must be
+// called before the nodes are started.
+func NewClientNode() *ClientNode {
+	client := &ClientNode{}
+	client.Init(uint32(len(core.Config.NodeChannelMap[core.ClientNodeType])))
+	core.Config.NodeChannelMap[core.ClientNodeType] = append(core.Config.NodeChannelMap[core.ClientNodeType], &client.Channel)
+	return client
+}
+
+// routeResponses sends each response received by the client to the request it answers. The
+// responses to the requests which have timed out are dropped.
+func (client *ClientNode) routeResponses() {
+	for response := range client.Channel.ResponseCommand {
+		client.pendingRequestMutex.Lock()
+		responseChannel, ok := client.pendingRequestMap[response.RequestId]
+		delete(client.pendingRequestMap, response.RequestId)
+		client.pendingRequestMutex.Unlock()
+		if !ok {
+			logger.Debug("Drop response to a request which has timed out",
+				zap.Uint32("clientId", client.Id),
+				zap.Uint64("requestId", response.RequestId),
+			)
+			continue
+		}
+		responseChannel <- response
+	}
+}
+
+// sendRequest sends a request to a scheduler with a new request id and waits until it is received and
+// answered at most for the given duration (nil if it has not answered)
+func (client *ClientNode) sendRequest(ctx context.Context, message core.RequestCommandRPC, timeout time.Duration) (*core.ResponseCommandRPC, error) {
+	message.RequestId = atomic.AddUint64(&client.lastRequestId, 1)
+	// Buffered so that routeResponses never waits for a request
+	responseChannel := make(chan core.ResponseCommandRPC, 1)
+	client.pendingRequestMutex.Lock()
+	client.pendingRequestMap[message.RequestId] = responseChannel
+	client.pendingRequestMutex.Unlock()
+	defer func() {
+		client.pendingRequestMutex.Lock()
+		delete(client.pendingRequestMap, message.RequestId)
+		client.pendingRequestMutex.Unlock()
+	}()
+
+	// The timeout covers both the request and the response, so that a node which does not read its
+	// requests anymore never blocks the client
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case core.Config.NodeChannelMap[core.SchedulerNodeType][message.ToNode.Id].RequestCommand <- message:
+	case <-timer.C:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case response := <-responseChannel:
+		return &response, nil
+	case <-timer.C:
+		return nil, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
 // sendMessageToLeader sends a message to the leader
-func (client *ClientNode) sendMessageToLeader(message core.RequestCommandRPC) (*core.ResponseCommandRPC, error) {
-	for i := 0; i < int(core.Config.MaxRetryToFindLeader); i++ {
-		requestChannel := core.Config.NodeChannelMap[core.SchedulerNodeType][client.LastLeaderId].RequestCommand
-		responseChannel := core.Config.NodeChannelMap[client.NodeCard.Type][client.NodeCard.Id].ResponseCommand
-		message.ToNode = core.NodeCard{Id: client.LastLeaderId, Type: core.SchedulerNodeType}
-		requestChannel <- message
-		select {
-		case response := <-responseChannel:
-			// If LeaderId given by node is -1
-			// it means that node does not know who is the leader
-			if response.LeaderId == core.NO_NODE {
-				logger.Warn("Leader is unknown. Check random node !",
-					zap.Uint32("tested nodeId", client.LastLeaderId),
-				)
-				client.LastLeaderId = core.GetRandomSchedulerNodeId()
-				continue
-			}
+func (client *ClientNode) sendMessageToLeader(ctx context.Context, message core.RequestCommandRPC) (*core.ResponseCommandRPC, error) {
+	return client.sendMessageToLeaderWithTimeout(ctx, message, core.Config.MaxFindLeaderTimeout)
+}
 
-			// Check if node connected to is still leader
-			if response.LeaderId != int(client.LastLeaderId) {
-				logger.Warn("Leader has changed",
-					zap.Uint32("old", client.LastLeaderId),
-					zap.Uint32("new", uint32(response.LeaderId)),
-				)
-				client.LastLeaderId = uint32(response.LeaderId)
-				continue
-			}
+// sendMessageToLeaderWithTimeout sends a message to the leader and waits for its response at most
+// for the given duration before trying another node. It can be called concurrently.
+func (client *ClientNode) sendMessageToLeaderWithTimeout(ctx context.Context, message core.RequestCommandRPC, timeout time.Duration) (*core.ResponseCommandRPC, error) {
+	for i := 0; i < int(core.Config.MaxRetryToFindLeader); i++ {
+		leaderId := atomic.LoadUint32(&client.LastLeaderId)
+		message.ToNode = core.NodeCard{Id: leaderId, Type: core.SchedulerNodeType}
+		response, err := client.sendRequest(ctx, message, timeout)
+		if err != nil {
+			return nil, err
+		}
 
-			// Else if the tested node is the leader
-			return &response, nil
-		case <-time.After(core.Config.MaxFindLeaderTimeout):
+		switch {
+		case response == nil:
 			logger.Warn("Node is not responding, trying to find new leader with random node...",
 				zap.Int("try", i+1),
-				zap.Uint32("tested nodeId", client.LastLeaderId),
+				zap.Uint32("tested nodeId", leaderId),
+			)
+			atomic.StoreUint32(&client.LastLeaderId, core.GetRandomSchedulerNodeId())
+		// If LeaderId given by node is -1
+		// it means that node does not know who is the leader
+		case response.LeaderId == core.NO_NODE:
+			logger.Warn("Leader is unknown. Check random node !",
+				zap.Uint32("tested nodeId", leaderId),
 			)
-			client.LastLeaderId = core.GetRandomSchedulerNodeId()
+			atomic.StoreUint32(&client.LastLeaderId, core.GetRandomSchedulerNodeId())
+		// Check if node connected to is still leader
+		case response.LeaderId != int(leaderId):
+			logger.Warn("Leader has changed",
+				zap.Uint32("old", leaderId),
+				zap.Uint32("new", uint32(response.LeaderId)),
+			)
+			atomic.StoreUint32(&client.LastLeaderId, uint32(response.LeaderId))
+		// The node has just lost the leadership and does not know the new leader yet, wait for its
+		// election. A request rejected by the leader always has a message.
+		case !response.Success && response.Message == "":
+			logger.Warn("Leader has just lost the leadership. Wait for a new leader !",
+				zap.Uint32("tested nodeId", leaderId),
+			)
+			if err := sleepContext(ctx, core.Config.MaxElectionTimeout); err != nil {
+				return nil, err
+			}
+			atomic.StoreUint32(&client.LastLeaderId, core.GetRandomSchedulerNodeId())
+		// Else if the tested node is the leader
+		default:
+			return response, nil
 		}
 	}
 	logger.Error("No response from leader after several tries")
-	return nil, errors.New("No response from leader after several tries")
+	return nil, ErrNoLeader
 }
 
 // handleSubmitCommand handles the submit job command
 func (client *ClientNode) handleSubmitCommand(tokenList []string) {
-	if len(tokenList) != 2 {
-		fmt.Println(SUBMIT_COMMAND_USAGE)
+	flagSet := newCommandFlagSet(SUBMIT_COMMAND)
+	options := newJobOptions()
+	options.register(flagSet)
+	atToken := flagSet.String("at", "", "time at which the job is executed")
+	waitFlag := flagSet.Bool("wait", false, "wait until the job has ended and print its outputs")
+	if err := flagSet.Parse(tokenList[1:]); err != nil || flagSet.NArg() != 1 || (*waitFlag && *atToken != "") {
+		client.printUsage(SUBMIT_COMMAND_USAGE)
 		return
 	}
 
-	if !client.ClusterIsStarted {
-		fmt.Println(NOT_STARTED_MESSAGE)
+	var startTime time.Time
+	if *atToken != "" {
+		var err error
+		if startTime, err = parseStartTime(*atToken, time.Now()); err != nil {
+			client.fail(err)
+			client.printUsage(SUBMIT_COMMAND_USAGE)
+			return
+		}
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
 		return
 	}
 
-	jobFilePath := tokenList[1]
-	fmt.Print("Submitting job ", jobFilePath, "... ")
-	input, loadErr := core.LoadCodeFromFile(jobFilePath)
-	if loadErr != nil {
-		fmt.Println("Error while loading job file : ", loadErr)
+	jobFilePath := flagSet.Arg(0)
+	client.printProgress("Submitting job ", jobFilePath, "... ")
+	options.stdinFile = client.resolveCommandPath(options.stdinFile)
+	job, err := loadJobFromFile(client.resolveCommandPath(jobFilePath), options, getSetOptionMap(flagSet))
+	if err != nil {
+		client.fail(err)
 		return
 	}
 
-	job := core.Job{
-		Input:    input,
-		WorkerId: core.NO_WORKER,
+	spec := JobSpec{Job: job, StartAt: startTime}
+	reference, err := client.Submit(context.Background(), spec)
+	if err != nil {
+		client.printRequestError(err)
+		return
 	}
-	entry := core.Entry{
-		Type: core.OpenJob,
-		Job:  job,
+	if *waitFlag {
+		client.printProgress(formatSubmitMessage(spec, reference, time.Now()), "\n")
+		client.waitForSubmittedJob(reference)
+		return
 	}
-	request := core.RequestCommandRPC{
-		FromNode:    client.NodeCard,
-		CommandType: core.AppendEntryCommand,
-		Entries:     []core.Entry{entry},
+	client.printSubmitResult(spec, reference)
+}
+
+// printSubmitResult prints the job or the schedule submitted to the leader
+func (client *ClientNode) printSubmitResult(spec JobSpec, reference Ref) {
+	switch {
+	case !client.outputFormat.IsStructured():
+		fmt.Fprintln(client.out, formatSubmitMessage(spec, reference, time.Now()))
+	case spec.IsSchedule():
+		client.printOutput(SubmitOutput{Schedule: reference})
+	default:
+		client.printOutput(SubmitOutput{Reference: reference})
+	}
+}
+
+// formatSubmitMessage describes a submitted job or schedule as the leader does. The next run of a
+// recurring schedule is computed again from the time of the submission.
+func formatSubmitMessage(spec JobSpec, reference Ref, now time.Time) string {
+	switch {
+	case spec.IsSchedule():
+		nextRun := spec.StartAt
+		if spec.Cron != "" {
+			if cron, err := core.ParseCronExpression(spec.Cron); err == nil {
+				nextRun = cron.Next(now)
+			}
+		}
+		return fmt.Sprintf("Schedule %s added. Next run at %s.", reference, nextRun.Format(time.RFC1123))
+	case spec.Job.IsArray():
+		return fmt.Sprintf("Job array %s of %d jobs submitted.", reference, spec.Job.ArraySize)
 	}
+	return fmt.Sprintf("Job %s submitted.", reference)
+}
 
-	response, sendErr := client.sendMessageToLeader(request)
-	if sendErr != nil {
-		fmt.Println("Error: ", sendErr)
+// waitForSubmittedJob waits until a submitted job has ended, then prints its result and its outputs
+func (client *ClientNode) waitForSubmittedJob(reference string) {
+	client.printProgress("Waiting for job ", reference, "... ")
+	jobMap, _, err := client.Wait(context.Background(), []string{reference}, 0)
+	if err != nil {
+		client.printError(err)
 		return
 	}
-	fmt.Println(response.Message)
+	job := jobMap[reference]
+	client.setJobsExitCode(jobMap, true)
+	if client.outputFormat.IsStructured() {
+		client.loadJobBlobs(&job, []core.BlobField{core.CompileLogBlobField, core.StdoutBlobField, core.StderrBlobField})
+		client.printOutput(newJobOutput(job, jobMap))
+		return
+	}
+	fmt.Fprintln(client.out, "Done.", formatJobResult(job))
+	if !job.IsArray() {
+		client.printJobOutputs(job)
+	}
 }
 
-// handleStatusCommand handles the status command
-func (client *ClientNode) handleStatusCommand(tokenList []string) {
-	if len(tokenList) > 2 {
-		fmt.Println(STATUS_COMMAND_USAGE)
+// handleWorkflowCommand handles the workflow command to submit a DAG of jobs
+func (client *ClientNode) handleWorkflowCommand(tokenList []string) {
+	if len(tokenList) != 2 {
+		client.printUsage(WORKFLOW_COMMAND_USAGE)
 		return
 	}
 
-	if !client.ClusterIsStarted {
-		fmt.Println(NOT_STARTED_MESSAGE)
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
 		return
 	}
 
-	// If the job reference is given
-	JobReference := ""
-	if len(tokenList) == 2 {
-		JobReference = tokenList[1]
+	workflowFilePath := tokenList[1]
+	client.printProgress("Submitting workflow ", workflowFilePath, "... ")
+	workflow, err := LoadWorkflowFromFile(client.resolveCommandPath(workflowFilePath))
+	if err != nil {
+		client.fail("Error while loading workflow file : ", err)
+		return
 	}
 
-	fmt.Print("Getting status... ")
+	// Jobs are submitted in topological order so that the reference of each parent is known
+	referenceMap := make(map[string]string)
+	for _, workflowJob := range workflow.Jobs {
+		job, err := workflowJob.ToJob(referenceMap)
+		if err != nil {
+			client.fail("Error while loading job", workflowJob.Name, ": ", err)
+			return
+		}
+		reference, err := client.Submit(context.Background(), JobSpec{Job: job})
+		var rejectedError *RejectedError
+		if errors.As(err, &rejectedError) {
+			client.fail("Error while submitting job", workflowJob.Name, ": ", rejectedError.Message)
+			return
+		} else if err != nil {
+			client.printError(err)
+			return
+		}
+		referenceMap[workflowJob.Name] = reference
+	}
 
-	request := core.RequestCommandRPC{
-		FromNode:    client.NodeCard,
-		CommandType: core.StatusCommand,
+	if client.outputFormat.IsStructured() {
+		outputList := make([]WorkflowJobOutput, 0, len(workflow.Jobs))
+		for _, workflowJob := range workflow.Jobs {
+			after := append([]string{}, workflowJob.After...)
+			outputList = append(outputList, WorkflowJobOutput{Name: workflowJob.Name, Reference: referenceMap[workflowJob.Name], After: after})
+		}
+		client.printOutput(outputList)
+		return
+	}
+	fmt.Fprintln(client.out, "Done.")
+	format := "%20s | %10s | %s\n"
+	fmt.Fprintf(client.out, format, "Name", "Reference", "After")
+	fmt.Fprintf(client.out, format, "--------------------", "----------", "----------")
+	for _, workflowJob := range workflow.Jobs {
+		fmt.Fprintf(client.out, format, workflowJob.Name, referenceMap[workflowJob.Name], strings.Join(workflowJob.After, ", "))
 	}
+}
 
-	response, err := client.sendMessageToLeader(request)
+// handleStatusCommand handles the status command
+func (client *ClientNode) handleStatusCommand(tokenList []string) {
+	// The job reference and the options can be given in any order
+	flagSet := newCommandFlagSet(STATUS_COMMAND)
+	options := statusOptions{}
+	options.register(flagSet)
+	stderrOnly := flagSet.Bool("stderr", false, "only print the standard error of the job")
+	argumentList, err := parseInterleavedFlags(flagSet, tokenList[1:])
+	if err != nil || len(argumentList) > 1 {
+		client.printUsage(STATUS_COMMAND_USAGE)
+		return
+	}
+	JobReference := ""
+	if len(argumentList) == 1 {
+		JobReference = argumentList[0]
+	}
+	// The options listing the jobs can not be given with a job reference
+	setOptionMap := getSetOptionMap(flagSet)
+	delete(setOptionMap, "stderr")
+	if (JobReference == "" && *stderrOnly) || (JobReference != "" && len(setOptionMap) > 0) {
+		client.printUsage(STATUS_COMMAND_USAGE)
+		return
+	}
+	query, err := options.buildJobQuery(time.Now())
 	if err != nil {
-		fmt.Println("Error: ", err)
+		client.fail(err)
+		client.printUsage(STATUS_COMMAND_USAGE)
+		return
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
 		return
 	}
 
-	JobMap := response.JobMap
+	client.printProgress("Getting status... ")
 
 	// If no argument is given, print the status of the cluster
 	if JobReference == "" {
-		fmt.Println("Done.")
-		printAllJobs(JobMap)
+		jobList, err := client.ListJobs(context.Background(), query)
+		if err != nil {
+			client.printError(err)
+			return
+		}
+		if client.outputFormat.IsStructured() {
+			client.printOutput(newClusterStatusOutput(query, jobList))
+			return
+		}
+		fmt.Fprintln(client.out, "Done.")
+		client.printAllJobs(jobList.Jobs)
+		client.printJobPage(query, jobList.JobCount)
+		if len(jobList.Schedules) > 0 {
+			fmt.Fprintln(client.out)
+			client.printAllSchedules(jobList.Schedules)
+		}
 		return
 	}
 
 	// Else, print the status of the given job
-	job, ok := JobMap[JobReference]
-	if !ok {
-		fmt.Println(INVALID_JOB_REFERENCE_MESSAGE)
+	job, JobMap, err := client.Status(context.Background(), JobReference)
+	if errors.Is(err, ErrJobNotFound) {
+		client.fail(INVALID_JOB_REFERENCE_MESSAGE)
+		return
+	} else if err != nil {
+		client.printError(err)
+		return
+	}
+	client.printProgress("Done.\n")
+	if *stderrOnly {
+		client.printJobStderr(job, JobMap)
+		return
+	}
+	if client.outputFormat.IsStructured() {
+		client.loadJobBlobs(&job, []core.BlobField{core.CompileLogBlobField, core.StdoutBlobField, core.StderrBlobField})
+		client.printOutput(newJobOutput(job, JobMap))
 		return
 	}
 	// Print all the job status
-	fmt.Println("Done.")
-	printJobStatus(job)
+	// The artifacts are only fetched by FETCH, their names are enough here
+	client.loadJobBlobs(&job, append(append([]core.BlobField{}, core.JobInputBlobFieldList...),
+		core.CompileLogBlobField, core.StdoutBlobField, core.StderrBlobField))
+	client.printJobStatus(job)
+	if job.IsArray() {
+		client.printArrayJobs(job, JobMap)
+	}
+}
+
+// handleCancelCommand handles the cancel command to cancel a job or a job array
+func (client *ClientNode) handleCancelCommand(tokenList []string) {
+	if len(tokenList) != 2 {
+		client.printUsage(CANCEL_COMMAND_USAGE)
+		return
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
+		return
+	}
+
+	job, err := core.ParseJobReference(tokenList[1])
+	if err != nil {
+		client.fail(err)
+		client.printUsage(CANCEL_COMMAND_USAGE)
+		return
+	}
 
+	fmt.Fprint(client.out, "Cancelling job ", job.GetReference(), "... ")
+	if err := client.Cancel(context.Background(), job.GetReference()); err != nil {
+		client.printRequestError(err)
+		return
+	}
+	fmt.Fprintf(client.out, "Job %s cancelled.\n", job.GetReference())
 }
 
-// printAllJobs prints all the jobs in the cluster
-func printAllJobs(JobMap map[string]core.Job) {
-	format := "%10s | %7s | %10s |\n"
-	fmt.Printf(format, "Reference", "Worker", "State")
-	fmt.Printf(format, "----------", "-------", "----------")
-	for reference, job := range JobMap {
-		fmt.Printf(format, reference, fmt.Sprint(job.WorkerId), job.State)
+// printAllJobs prints the summaries of the jobs listed by the leader, in their order
+func (client *ClientNode) printAllJobs(summaryList []core.JobSummary) {
+	format := "%14s | %7s | %8s | %10s | %11s | %19s |\n"
+	fmt.Fprintf(client.out, format, "Reference", "Worker", "Priority", "State", "Array", "Submitted")
+	fmt.Fprintf(client.out, format, "--------------", "-------", "--------", "----------", "-----------", "-------------------")
+	for _, summary := range summaryList {
+		array := "-"
+		if summary.IsArray() {
+			array = fmt.Sprintf("%d/%d ended", summary.ArrayEndedCount, summary.ArraySize)
+		}
+		fmt.Fprintf(client.out, format, summary.Reference, fmt.Sprint(summary.WorkerId), summary.Priority, summary.State, array,
+			summary.SubmittedAt.Format("2006-01-02 15:04:05"))
+	}
+}
+
+// printJobPage prints the position of the listed jobs among the jobs matching the query
+func (client *ClientNode) printJobPage(query core.JobQuery, jobCount uint32) {
+	limit := query.GetLimit()
+	if query.Offset == 0 && jobCount <= limit {
+		return
+	}
+	page := query.Offset/limit + 1
+	pageCount := (jobCount + limit - 1) / limit
+	fmt.Fprintf(client.out, "Page %d of %d (%d jobs).", page, pageCount, jobCount)
+	if page < pageCount {
+		fmt.Fprintf(client.out, " Run STATUS with `--page %d` to see the next jobs.", page+1)
+	}
+	fmt.Fprintln(client.out)
+}
+
+// getArrayEndedCount returns the number of jobs of a job array which have ended
+func getArrayEndedCount(array core.Job) uint32 {
+	if array.ArrayStateCount == nil {
+		return 0
+	}
+	return array.ArraySize - array.ArrayStateCount[core.JobWaiting]
+}
+
+// printArrayJobs prints the jobs of a job array
+func (client *ClientNode) printArrayJobs(array core.Job, JobMap map[string]core.Job) {
+	format := "%14s | %7s | %10s | %8s |\n"
+	fmt.Fprintf(client.out, format, "Reference", "Worker", "State", "Attempts")
+	fmt.Fprintf(client.out, format, "--------------", "-------", "----------", "--------")
+	for index := array.ArrayStart; index < array.ArrayStart+int(array.ArraySize); index++ {
+		reference := array.GetArrayChildReference(index)
+		job := JobMap[reference]
+		fmt.Fprintf(client.out, format, reference, fmt.Sprint(job.WorkerId), job.State, fmt.Sprint(len(job.Attempts)))
 	}
 }
 
 // printJobStatus prints the status of a given job
-func printJobStatus(job core.Job) {
-	fmt.Println("### JOB STATUS ###")
-	fmt.Println("> Reference : ", job.GetReference())
-	fmt.Println("> Worker Id : ", job.WorkerId)
-	fmt.Println("> State : ", job.State)
-	fmt.Println("-- Input --\n", job.Input)
-	fmt.Println("\n\n-- Output --\n", job.Output)
-	fmt.Println("\n\n##################")
+func (client *ClientNode) printJobStatus(job core.Job) {
+	fmt.Fprintln(client.out, "### JOB STATUS ###")
+	fmt.Fprintln(client.out, "> Reference : ", job.GetReference())
+	fmt.Fprintln(client.out, "> Worker Id : ", job.WorkerId)
+	fmt.Fprintln(client.out, "> Priority : ", job.Priority)
+	fmt.Fprintln(client.out, "> Submitted at : ", job.SubmittedAt.Format(time.RFC1123))
+	if len(job.Labels) > 0 {
+		fmt.Fprintln(client.out, "> Labels : ", strings.Join(formatKeyValueMap(job.Labels), " "))
+	}
+	fmt.Fprintln(client.out, "> Language : ", job.GetLanguage())
+	fmt.Fprintln(client.out, "> Compiler : ", strings.Join(append([]string{job.GetCompiler()}, job.CompilerFlags...), " "))
+	if job.HasArchive() {
+		if job.BuildCommand != "" {
+			fmt.Fprintln(client.out, "> Build command : ", job.BuildCommand)
+		}
+		fmt.Fprintln(client.out, "> Executable : ", job.GetExecutable())
+	}
+	if job.ScheduleReference != "" {
+		fmt.Fprintln(client.out, "> Schedule : ", job.ScheduleReference)
+	}
+	if job.IsArray() {
+		fmt.Fprintf(client.out, "> Array :  %d jobs (index %d to %d), %d ended\n",
+			job.ArraySize, job.ArrayStart, job.ArrayStart+int(job.ArraySize)-1, getArrayEndedCount(job))
+		for state, count := range job.ArrayStateCount {
+			fmt.Fprintln(client.out, "  ", state, ":", count)
+		}
+	}
+	if job.IsArrayChild() {
+		fmt.Fprintln(client.out, "> Array : ", job.ArrayParent, "index", job.ArrayIndex)
+	}
+	if len(job.Dependencies) > 0 {
+		fmt.Fprintln(client.out, "> After : ", strings.Join(job.Dependencies, ", "))
+	}
+	if len(job.Args) > 0 {
+		fmt.Fprintf(client.out, "> Arguments :  %q\n", job.Args)
+	}
+	if len(job.Env) > 0 {
+		fmt.Fprintln(client.out, "> Environment : ", strings.Join(job.GetEnvList(), " "))
+	}
+	if len(job.ArtifactPatterns) > 0 {
+		fmt.Fprintln(client.out, "> Artifact patterns : ", strings.Join(job.ArtifactPatterns, " "))
+	}
+	if job.Timeout > 0 {
+		fmt.Fprintln(client.out, "> Timeout : ", job.Timeout)
+	}
+	if job.CPULimit > 0 {
+		fmt.Fprintln(client.out, "> CPU limit : ", job.CPULimit)
+	}
+	if job.MemoryLimit > 0 {
+		fmt.Fprintln(client.out, "> Memory limit : ", core.FormatMemorySize(job.MemoryLimit))
+	}
+	if job.CPUQuota > 0 {
+		fmt.Fprintln(client.out, "> CPU quota : ", job.CPUQuota, "CPUs")
+	}
+	fmt.Fprintln(client.out, "> Retry policy : ", job.RetryPolicy)
+	if job.State == core.JobWaiting && !job.RetryAt.IsZero() {
+		fmt.Fprintln(client.out, "> Next retry : ", job.RetryAt.Format(time.StampMilli))
+	}
+	fmt.Fprintln(client.out, "> Attempts : ", len(job.Attempts))
+	for _, attempt := range job.Attempts {
+		fmt.Fprintln(client.out, "  ", attempt)
+	}
+	fmt.Fprintln(client.out, "> State : ", job.State)
+	if len(job.Attempts) > 0 && job.ExitCode != core.NO_EXIT_CODE {
+		fmt.Fprintln(client.out, "> Exit code : ", job.ExitCode)
+	}
+	if job.Signal != "" {
+		fmt.Fprintln(client.out, "> Signal : ", job.Signal)
+	}
+	if job.Archive == nil && job.HasArchive() {
+		// The archive could not be fetched from its blob store
+		fmt.Fprintln(client.out, "-- Files --\n", job.Blobs[core.ArchiveBlobField])
+	} else if job.Archive != nil {
+		fileList, err := core.ListArchive(job.Archive)
+		if err != nil {
+			fmt.Fprintln(client.out, "-- Files --\n", "Invalid archive: ", err)
+		} else {
+			fmt.Fprintln(client.out, "-- Files --\n", strings.Join(fileList, "\n "))
+		}
+	} else {
+		fmt.Fprintln(client.out, "-- Input --\n", job.Input)
+	}
+	if job.Stdin != "" {
+		fmt.Fprintln(client.out, "\n\n-- Stdin --\n", job.Stdin)
+	}
+	if job.CompileLog != "" {
+		fmt.Fprintln(client.out, "\n\n-- Compile log --\n", job.CompileLog)
+	}
+	fmt.Fprintln(client.out, "\n\n-- Stdout --\n", job.Stdout)
+	fmt.Fprintln(client.out, "\n\n-- Stderr --\n", job.Stderr)
+	if len(job.ArtifactList) > 0 {
+		fmt.Fprintln(client.out, "\n\n-- Artifacts --\n", strings.Join(job.ArtifactList, "\n "))
+	}
+	fmt.Fprintln(client.out, "\n\n##################")
+
+}
+
+// printJobStderr prints the standard error of a job, or of each job of a job array
+func (client *ClientNode) printJobStderr(job core.Job, JobMap map[string]core.Job) {
+	if !job.IsArray() {
+		client.loadJobBlobs(&job, []core.BlobField{core.StderrBlobField})
+		fmt.Fprintln(client.out, strings.TrimSuffix(job.Stderr, "\n"))
+		return
+	}
+	for index := job.ArrayStart; index < job.ArrayStart+int(job.ArraySize); index++ {
+		reference := job.GetArrayChildReference(index)
+		child := JobMap[reference]
+		client.loadJobBlobs(&child, []core.BlobField{core.StderrBlobField})
+		fmt.Fprintf(client.out, "-- %s --\n", reference)
+		fmt.Fprintln(client.out, strings.TrimSuffix(child.Stderr, "\n"))
+	}
+}
 
+// loadJobBlobs fetches the given fields of a job stored in blob stores. The fields which can not be
+// fetched are left empty.
+func (client *ClientNode) loadJobBlobs(job *core.Job, fieldList []core.BlobField) {
+	for _, field := range fieldList {
+		if err := job.LoadBlobs(client.blobStore, []core.BlobField{field}); err != nil {
+			client.printError(err)
+		}
+	}
 }
 
 // handleStartCommand handles the start cluster command
 func (client *ClientNode) handleCrashCommand(tokenList []string) {
 	if len(tokenList) != 2 {
-		fmt.Println(CRASH_COMMAND_USAGE)
+		client.printUsage(CRASH_COMMAND_USAGE)
 		return
 	}
 	nodeId, err := parseNodeNumber(tokenList[1])
 	if err != nil {
-		fmt.Println(err)
+		client.fail(err)
 		return
 	}
-	fmt.Print("Crashing the node ", nodeId, "... ")
+	fmt.Fprint(client.out, "Crashing the node ", nodeId, "... ")
 	logger.Warn("Crash a node", zap.Uint32("nodeId", nodeId))
 	request := core.RequestCommandRPC{
 		FromNode:    client.NodeCard,
@@ -240,21 +691,21 @@ func (client *ClientNode) handleCrashCommand(tokenList []string) {
 		CommandType: core.CrashCommand,
 	}
 	core.Config.NodeChannelMap[core.SchedulerNodeType][nodeId].RequestCommand <- request
-	fmt.Println("Done.")
+	fmt.Fprintln(client.out, "Done.")
 }
 
 // handleRecoverCommand handles the recover command
 func (client *ClientNode) handleRecoverCommand(tokenList []string) {
 	if len(tokenList) != 2 {
-		fmt.Println(RECOVER_COMMAND_USAGE)
+		client.printUsage(RECOVER_COMMAND_USAGE)
 		return
 	}
 	nodeId, err := parseNodeNumber(tokenList[1])
 	if err != nil {
-		fmt.Println(err)
+		client.fail(err)
 		return
 	}
-	fmt.Print("Recovering the node ", nodeId, "... ")
+	fmt.Fprint(client.out, "Recovering the node ", nodeId, "... ")
 	logger.Warn("Recover a node", zap.Uint32("nodeId", nodeId))
 	request := core.RequestCommandRPC{
 		FromNode:    client.NodeCard,
@@ -262,13 +713,13 @@ func (client *ClientNode) handleRecoverCommand(tokenList []string) {
 		CommandType: core.RecoverCommand,
 	}
 	core.Config.NodeChannelMap[core.SchedulerNodeType][nodeId].RequestCommand <- request
-	fmt.Println("Done.")
+	fmt.Fprintln(client.out, "Done.")
 }
 
 // handleSpeedCommand handles the speed command
 func (client *ClientNode) handleSpeedCommand(tokenList []string) {
 	if len(tokenList) != 3 {
-		fmt.Println(SPEED_COMMAND_USAGE)
+		client.printUsage(SPEED_COMMAND_USAGE)
 		return
 	}
 	levelToken := strings.ToLower(tokenList[1])
@@ -282,30 +733,30 @@ func (client *ClientNode) handleSpeedCommand(tokenList []string) {
 	case HIGH_SPEED.String():
 		latency = core.HighNodeSpeed
 	default:
-		fmt.Println(INVALID_SPEED_LEVEL_MESSAGE)
-		fmt.Println(SPEED_COMMAND_USAGE)
+		client.fail(INVALID_SPEED_LEVEL_MESSAGE)
+		client.printUsage(SPEED_COMMAND_USAGE)
 		return
 	}
 
 	nodeId, err := parseNodeNumber(tokenList[2])
 	if err != nil {
-		fmt.Println(err)
+		client.fail(err)
 		return
 	}
-	fmt.Print("Setting speed to ", levelToken, " for node ", nodeId, "... ")
+	fmt.Fprint(client.out, "Setting speed to ", levelToken, " for node ", nodeId, "... ")
 	logger.Info("Change Speed for a node",
 		zap.Uint32("nodeId", nodeId),
 		zap.String("speed", levelToken),
 		zap.Duration("latency", latency),
 	)
 	core.Config.NodeSpeedList[nodeId] = latency
-	fmt.Println("Done.")
+	fmt.Fprintln(client.out, "Done.")
 }
 
 // handleStartCommand handles the start cluster command
 func (client *ClientNode) handleStartCommand() {
-	fmt.Print("Starting all nodes... ")
-	client.ClusterIsStarted = true
+	fmt.Fprint(client.out, "Starting all nodes... ")
+	atomic.StoreInt32(&clusterIsStarted, 1)
 	for index, channelContainer := range core.Config.NodeChannelMap[core.SchedulerNodeType] {
 		request := core.RequestCommandRPC{
 			FromNode:    client.NodeCard,
@@ -314,17 +765,28 @@ func (client *ClientNode) handleStartCommand() {
 		}
 		channelContainer.RequestCommand <- request
 	}
-	fmt.Println("Done.")
+	fmt.Fprintln(client.out, "Done.")
 }
 
 // handleStopCommand handles the stop cluster command
 func (client *ClientNode) handleCommand(command string) {
-	tokenList := strings.Fields(command)
-	if len(tokenList) == 0 {
-		fmt.Println(INVALID_COMMAND_MESSAGE)
-		fmt.Println(HELP_MESSAGE)
+	tokenList, err := splitCommandLine(command)
+	if err != nil {
+		client.fail(err)
 		return
 	}
+	client.RunCommand(tokenList)
+}
+
+// RunCommand runs a command given as a list of tokens, the name of the command first, and returns
+// its exit code (see EXIT_SUCCESS)
+func (client *ClientNode) RunCommand(tokenList []string) int {
+	client.exitCode = EXIT_SUCCESS
+	if len(tokenList) == 0 {
+		client.printUsage(INVALID_COMMAND_MESSAGE)
+		fmt.Fprintln(client.out, HELP_MESSAGE)
+		return client.exitCode
+	}
 
 	commandToken := strings.ToUpper(tokenList[0])
 	switch commandToken {
@@ -340,13 +802,32 @@ func (client *ClientNode) handleCommand(command string) {
 		client.handleSubmitCommand(tokenList)
 	case STATUS_COMMAND.String():
 		client.handleStatusCommand(tokenList)
+	case WORKFLOW_COMMAND.String():
+		client.handleWorkflowCommand(tokenList)
+	case SCHEDULE_COMMAND.String():
+		client.handleScheduleCommand(tokenList)
+	case UNSCHEDULE_COMMAND.String():
+		client.handleUnscheduleCommand(tokenList)
+	case CANCEL_COMMAND.String():
+		client.handleCancelCommand(tokenList)
+	case TAIL_COMMAND.String():
+		client.handleTailCommand(tokenList)
+	case FETCH_COMMAND.String():
+		client.handleFetchCommand(tokenList)
+	case WAIT_COMMAND.String():
+		client.handleWaitCommand(tokenList)
+	case WATCH_COMMAND.String():
+		client.handleWatchCommand(tokenList)
 	case STOP_COMMAND.String():
-		fmt.Println("Stopping all nodes...")
-		os.Exit(0)
+		fmt.Fprintln(client.out, "Stopping all nodes...")
+		client.stop()
+	case FORMAT_COMMAND.String():
+		client.handleFormatCommand(tokenList)
 	case HELP_COMMAND.String():
-		fmt.Println(HELP_MESSAGE)
+		fmt.Fprintln(client.out, HELP_MESSAGE)
 	default:
-		fmt.Println(INVALID_COMMAND_MESSAGE)
-		fmt.Println(HELP_MESSAGE)
+		client.printUsage(INVALID_COMMAND_MESSAGE)
+		fmt.Fprintln(client.out, HELP_MESSAGE)
 	}
+	return client.exitCode
 }
@@ -1,8 +1,12 @@
 package client
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 
 	"github.com/Timelessprod/algorep/pkg/core"
 )
@@ -15,14 +19,23 @@ import (
 type CommandType string
 
 const (
-	SPEED_COMMAND   CommandType = "SPEED"
-	CRASH_COMMAND   CommandType = "CRASH"
-	START_COMMAND   CommandType = "START"
-	SUBMIT_COMMAND  CommandType = "SUBMIT"
-	STATUS_COMMAND  CommandType = "STATUS"
-	STOP_COMMAND    CommandType = "STOP"
-	RECOVER_COMMAND CommandType = "RECOVER"
-	HELP_COMMAND    CommandType = "HELP"
+	SPEED_COMMAND      CommandType = "SPEED"
+	CRASH_COMMAND      CommandType = "CRASH"
+	START_COMMAND      CommandType = "START"
+	SUBMIT_COMMAND     CommandType = "SUBMIT"
+	STATUS_COMMAND     CommandType = "STATUS"
+	STOP_COMMAND       CommandType = "STOP"
+	RECOVER_COMMAND    CommandType = "RECOVER"
+	WORKFLOW_COMMAND   CommandType = "WORKFLOW"
+	SCHEDULE_COMMAND   CommandType = "SCHEDULE"
+	UNSCHEDULE_COMMAND CommandType = "UNSCHEDULE"
+	CANCEL_COMMAND     CommandType = "CANCEL"
+	TAIL_COMMAND       CommandType = "TAIL"
+	FETCH_COMMAND      CommandType = "FETCH"
+	WAIT_COMMAND       CommandType = "WAIT"
+	WATCH_COMMAND      CommandType = "WATCH"
+	FORMAT_COMMAND     CommandType = "FORMAT"
+	HELP_COMMAND       CommandType = "HELP"
 )
 
 // Convert a CommandType to a string
@@ -53,26 +66,128 @@ func (c SpeedCommandType) String() string {
  *******************/
 
 const (
-	HELP_MESSAGE = `You can use 8 commands :
+	HELP_MESSAGE = `You can use 17 commands :
 	- SPEED (low|medium|high) <node number> : change the speed of a node. For example: 'SPEED high 2' will change the speed of node 2 to high.
 	- CRASH <node number> : crash a node. For example: 'CRASH 2' will crash node 2.
 	- RECOVER <node number> : recover a crashed node. For example: 'RECOVER 2' will recover node 2.
 	- START : start the cluster. You can use this command only once.
-	- SUBMIT <job file> : submit a job to the cluster. The cluster must be STARTed before. For example: 'SUBMIT path/job.cpp' will submit the job described in the file job.cpp.
-	- STATUS [<job reference>] : display the status of the cluster or of a specific job. For example: 'STATUS' will display the status of the cluster. 'STATUS 1-2' will display the status of the job with reference 1-2.
+	- SUBMIT [options] <job file> : submit a job to the cluster. The cluster must be STARTed before. For example: 'SUBMIT path/job.cpp' will submit the job described in the file job.cpp. The job file can also be a job manifest (.yaml, .yml or .json) describing the sources and the options of the job, which are overridden by the options of the command line, or a directory or a tar archive (.tar, .tar.gz or .tgz) of a multi-file job. Options:
+		--wait : wait until the job has ended, then display its result and its outputs. It can not be used with --at. For example: 'SUBMIT --wait path/job.cpp'.
+		--at <time> : execute the job at the given time instead of now. The time can be 'HH:MM[:SS]' (the next occurrence), 'YYYY-MM-DD HH:MM[:SS]' (between quotes), RFC 3339 or '+<duration>'. For example: 'SUBMIT --at 23:30 path/job.cpp' or 'SUBMIT --at +10m path/job.cpp'.
+		--priority (low|medium|high) : queued jobs with a lower priority are executed after this job (default: medium). For example: 'SUBMIT --priority high path/job.cpp'.
+		--after <job reference>[,<job reference>] : execute the job only once the given jobs have succeeded. For example: 'SUBMIT --after 1-2,3-2 path/job.cpp'.
+		--array <first index>-<last index> : submit a job array, which executes the job once for each index. The index is given to the job in the JOB_ARRAY_INDEX environment variable and each job of the array has the reference '<array reference>_<index>'. For example: 'SUBMIT --array 1-100 path/job.cpp'.
+		--label <KEY>=<VALUE> : describe the job with a label. It can be repeated. For example: 'SUBMIT --label team=benchmark path/job.cpp'.
+		--language (c++|c) : language of the job (default: c++).
+		--compiler <compiler> : compiler of the job, among the compilers allowed on the workers (default: g++). For example: 'SUBMIT --compiler clang++ path/job.cpp'.
+		--compiler-flag <flag> : give a flag to the compiler, among the flags allowed on the workers. It can be repeated. For example: 'SUBMIT --compiler-flag -O2 --compiler-flag -std=c++20 path/job.cpp'.
+		--build <command> : command building a multi-file job (default: make if there is a Makefile, otherwise all the source files are compiled). For example: 'SUBMIT --build "cmake . && make" path/project'.
+		--executable <path> : executable built by a multi-file job (default: job.out). For example: 'SUBMIT --executable bin/app path/project'.
+		--arg <argument> : give an argument to the job. It can be repeated. For example: 'SUBMIT --arg 10 --arg "hello world" path/job.cpp'.
+		--env <KEY>=<VALUE> : give an environment variable to the job. It can be repeated. For example: 'SUBMIT --env N=10 path/job.cpp'.
+		--stdin <file> : give the content of a file to the job on its standard input. For example: 'SUBMIT --stdin path/input.txt path/job.cpp'.
+		--artifact <pattern> : collect the files of the workspace of the job matching the pattern after its execution, to download them with FETCH. A directory is collected with all its files. It can be repeated. For example: 'SUBMIT --artifact "results/*.csv" path/job.cpp'.
+		--timeout <duration> : kill the job if its execution takes longer. For example: 'SUBMIT --timeout 30s path/job.cpp'.
+		--cpu-limit <duration> : kill the job if it uses more CPU time. For example: 'SUBMIT --cpu-limit 10s path/job.cpp'.
+		--memory-limit <size> : limit the memory of the job. For example: 'SUBMIT --memory-limit 256M path/job.cpp'.
+		--cpus <number> : limit the number of CPUs used by the job, on the workers using cgroups. For example: 'SUBMIT --cpus 0.5 path/job.cpp'.
+		--max-attempts <number> : execute the job again when it fails, up to this number of attempts (default: 1).
+		--backoff (fixed|exponential) : wait the same delay before each retry, or double it at each retry (default: fixed).
+		--retry-delay <duration> : delay before the first retry (default: 1s).
+		--retry-on <exit code>[,<exit code>] : retry only for these exit codes (default: all non-zero exit codes).
+		--retry-on-timeout : also retry the job when it exceeds its timeout.
+		--retry-on-signal : also retry the job when it is killed by a signal, for example when it exceeds its memory limit.
+	- WORKFLOW <workflow file> : submit a workflow of jobs depending on each other. For example: 'WORKFLOW path/workflow.json' will submit the jobs described in the file workflow.json.
+	- SCHEDULE "<cron expression>" [options] <job file> : submit a job periodically according to a cron expression (minute, hour, day of month, month, day of week). The SUBMIT options can be used. For example: 'SCHEDULE "*/5 * * * *" path/job.cpp' will submit the job every 5 minutes.
+	- UNSCHEDULE <schedule reference> : stop a schedule. For example: 'UNSCHEDULE S1-2'.
+	- CANCEL <job reference> : cancel a job, or all the jobs of a job array, which has not ended yet. For example: 'CANCEL 1-2' or 'CANCEL 1-2_5'.
+	- STATUS [options] [<job reference>] : display the status of the cluster or of a specific job. For example: 'STATUS' will display a summary of the jobs and the schedules of the cluster, by pages of 50 jobs. 'STATUS 1-2' will display the details of the job with reference 1-2. Options:
+		--state <state>[,<state>] : only list the jobs in these states. For example: 'STATUS --state failed,cancelled'.
+		--worker <worker number>[,<worker number>] : only list the jobs of these workers.
+		--label <KEY>=<VALUE> : only list the jobs with this label. It can be repeated.
+		--submitter <client number>[,<client number>] : only list the jobs submitted by these clients.
+		--since <time> and --until <time> : only list the jobs submitted in this range of time. The time can be 'HH:MM[:SS]' (the last occurrence), 'YYYY-MM-DD HH:MM[:SS]' (between quotes), RFC 3339 or '-<duration>'. For example: 'STATUS --since -1h'.
+		--array-jobs : list the jobs of the job arrays, which are summarized by their array otherwise.
+		--sort (reference|submitted|state|priority|worker) : sort the jobs by this field (default: reference), and --desc to sort them in descending order. For example: 'STATUS --sort submitted --desc'.
+		--page <number> and --limit <number> : list this page of jobs, with this number of jobs per page (default: 1 and 50). For example: 'STATUS --page 2'.
+		--stderr : only display the standard error of the job, or of each job of a job array. For example: 'STATUS 1-2 --stderr'.
+	- FETCH <job reference> <directory> : download the artifacts of a job which has ended into a directory, or those of each job of a job array into a subdirectory named after its reference. For example: 'FETCH 1-2 results'.
+	- WAIT (<job reference>|ALL) [<timeout>] : wait until a job, or all the jobs of the cluster, have ended, or until the timeout has passed. For example: 'WAIT 1-2', 'WAIT 1-2 30s' or 'WAIT ALL 5m'.
+	- WATCH [options] [<job reference>] : display the state changes of a job, of a job array and its jobs, or of all the jobs, as the leader applies them, until the job has ended or for a given duration (default: 1m when no job is given). For example: 'WATCH 1-2' or 'WATCH --label team=benchmark --for 10m'. Options:
+		--label <KEY>=<VALUE> : only watch the jobs with this label. It can be repeated.
+		--from <log index> : first display the state changes caused by the entries of the log after this index. For example: 'WATCH --from 0 --for 30s' displays all the state changes since the start of the cluster.
+		--for <duration> : stop watching after this duration.
+	- TAIL <job reference> : follow the standard output and error of a job while it runs, until it ends. For example: 'TAIL 1-2' or 'TAIL 1-2_5'.
+	- FORMAT (table|json|yaml) : set the format in which SUBMIT, SCHEDULE, WORKFLOW, STATUS and WAIT print their result (default: table). In the json and yaml formats, they only print their result, and their errors on the standard error. For example: 'FORMAT json'.
 	- STOP : stop the cluster. This command will kill the program.
 	- HELP : display this message.`
 	SPEED_COMMAND_USAGE           = "The SPEED command must have the following form: `SPEED (low|medium|high) <node number>`. For example: 'SPEED high 2'"
 	CRASH_COMMAND_USAGE           = "The CRASH command must have the following form: `CRASH <node number>`. For example: 'CRASH 2'"
-	SUBMIT_COMMAND_USAGE          = "The SUBMIT command must have the following form: `SUBMIT <job file>`. For example: 'SUBMIT path/job.cpp'"
+	SUBMIT_COMMAND_USAGE          = "The SUBMIT command must have the following form: `SUBMIT [options] <job file>` where the options must be given before the job file. For example: 'SUBMIT path/job.cpp', 'SUBMIT path/job.yaml' or 'SUBMIT --priority high --after 1-2 --max-attempts 3 path/job.cpp'. Run HELP to see all the options."
+	SCHEDULE_COMMAND_USAGE        = "The SCHEDULE command must have the following form: `SCHEDULE \"<cron expression>\" [options] <job file>`. For example: 'SCHEDULE \"*/5 * * * *\" path/job.cpp' or 'SCHEDULE @hourly --priority low path/job.cpp'"
+	CANCEL_COMMAND_USAGE          = "The CANCEL command must have the following form: `CANCEL <job reference>`. For example: 'CANCEL 1-2' or 'CANCEL 1-2_5'"
+	UNSCHEDULE_COMMAND_USAGE      = "The UNSCHEDULE command must have the following form: `UNSCHEDULE <schedule reference>`. For example: 'UNSCHEDULE S1-2'"
+	WORKFLOW_COMMAND_USAGE        = "The WORKFLOW command must have the following form: `WORKFLOW <workflow file>`. For example: 'WORKFLOW path/workflow.json'"
 	RECOVER_COMMAND_USAGE         = "The RECOVER command must have the following form: `RECOVER <node number>`. For example: 'RECOVER 2'"
-	STATUS_COMMAND_USAGE          = "The STATUS command must have the following form: `STATUS` or `STATUS <JobReference>`. For example: 'STATUS' or 'STATUS 1-2'"
-	INVALID_JOB_REFERENCE_MESSAGE = "Job not found ! Please make sure you have provided a valid reference. The job reference must have the following form: `<JobId>-<Term>`. For example: '1-2'"
+	STATUS_COMMAND_USAGE          = "The STATUS command must have the following form: `STATUS [options]`, `STATUS <JobReference>` or `STATUS <JobReference> --stderr`. For example: 'STATUS', 'STATUS --state waiting --sort submitted --page 2', 'STATUS 1-2' or 'STATUS 1-2 --stderr'. Run HELP to see all the options."
+	FETCH_COMMAND_USAGE           = "The FETCH command must have the following form: `FETCH <job reference> <directory>`. For example: 'FETCH 1-2 results'"
+	WAIT_COMMAND_USAGE            = "The WAIT command must have the following form: `WAIT <job reference> [<timeout>]` or `WAIT ALL [<timeout>]`. For example: 'WAIT 1-2', 'WAIT 1-2 30s' or 'WAIT ALL 5m'"
+	WATCH_COMMAND_USAGE           = "The WATCH command must have the following form: `WATCH [options] [<job reference>]` where the options are `--label <KEY>=<VALUE>`, `--from <log index>` and `--for <duration>`. For example: 'WATCH 1-2', 'WATCH --for 5m' or 'WATCH --label team=benchmark --from 0 --for 30s'"
+	TAIL_COMMAND_USAGE            = "The TAIL command must have the following form: `TAIL <job reference>`. For example: 'TAIL 1-2' or 'TAIL 1-2_5'"
+	FORMAT_COMMAND_USAGE          = "The FORMAT command must have the following form: `FORMAT (table|json|yaml)`. For example: 'FORMAT json'"
+	TAIL_ARRAY_MESSAGE            = "A job array can not be followed. Follow one of its jobs with its reference `<array reference>_<index>`. For example: 'TAIL 1-2_5'"
+	INVALID_JOB_REFERENCE_MESSAGE = "Job not found ! Please make sure you have provided a valid reference. The job reference must have the following form: `<JobId>-<Term>` or `<JobId>-<Term>_<Index>` for a job of a job array. For example: '1-2' or '1-2_5'"
 	INVALID_COMMAND_MESSAGE       = "Invalid command !"
 	INVALID_SPEED_LEVEL_MESSAGE   = "Invalid speed level !"
 	NOT_STARTED_MESSAGE           = "Cluster is not started yet ! Run the START command first."
 )
 
+/****************
+ ** Exit Codes **
+ ****************/
+
+// Exit codes of the commands, returned by the one-shot commands of the command line
+const (
+	EXIT_SUCCESS = 0
+	// The command has failed: job not found, invalid job file, no leader, ...
+	EXIT_ERROR = 1
+	// The arguments of the command are invalid
+	EXIT_USAGE = 2
+	// The awaited job, or one of the awaited jobs, has ended without succeeding
+	EXIT_JOB_FAILED = 3
+	// The timeout of the WAIT command has passed before the jobs have ended
+	EXIT_TIMEOUT = 4
+)
+
+// printUsage prints the usage of a command given invalid arguments
+func (client *ClientNode) printUsage(usage string) {
+	fmt.Fprintln(client.errorOut(), usage)
+	client.exitCode = EXIT_USAGE
+}
+
+// printError prints the error of a command which has failed
+func (client *ClientNode) printError(err error) {
+	fmt.Fprintln(client.errorOut(), "Error: ", err)
+	client.exitCode = EXIT_ERROR
+}
+
+// fail prints why a command has failed
+func (client *ClientNode) fail(message ...interface{}) {
+	fmt.Fprintln(client.errorOut(), message...)
+	client.exitCode = EXIT_ERROR
+}
+
+// printRequestError prints why a request has failed, with the message of the leader if it has
+// rejected the request
+func (client *ClientNode) printRequestError(err error) {
+	var rejectedError *RejectedError
+	if errors.As(err, &rejectedError) {
+		client.fail(rejectedError.Message)
+		return
+	}
+	client.printError(err)
+}
+
 // ParseNodeNumber parses the node number from a command
 func parseNodeNumber(token string) (uint32, error) {
 	nodeId, err := strconv.ParseUint(token, 0, 32)
@@ -84,3 +199,74 @@ func parseNodeNumber(token string) (uint32, error) {
 	}
 	return uint32(nodeId), nil
 }
+
+// splitCommandLine splits a command line in tokens separated by spaces. Spaces between double
+// or single quotes are kept, for example to give a cron expression as a single token.
+func splitCommandLine(command string) ([]string, error) {
+	var tokenList []string
+	var token strings.Builder
+	inToken := false
+	var quote rune
+	for _, char := range command {
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			token.WriteRune(char)
+		case char == '"' || char == '\'':
+			quote = char
+			inToken = true
+		case char == ' ' || char == '\t':
+			if inToken {
+				tokenList = append(tokenList, token.String())
+				token.Reset()
+				inToken = false
+			}
+		default:
+			token.WriteRune(char)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("Missing closing quote %c", quote)
+	}
+	if inToken {
+		tokenList = append(tokenList, token.String())
+	}
+	return tokenList, nil
+}
+
+// splitCommaSeparatedList splits a comma separated list (job references, exit codes, ...)
+func splitCommaSeparatedList(token string) []string {
+	var itemList []string
+	for _, item := range strings.Split(token, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			itemList = append(itemList, item)
+		}
+	}
+	return itemList
+}
+
+// newCommandFlagSet creates a flag set to parse the options of a command. Errors are not printed
+// because the REPL prints the usage of the command itself.
+func newCommandFlagSet(command CommandType) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(command.String(), flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	return flagSet
+}
+
+// parseInterleavedFlags parses the options of a command which can be given before or after its
+// arguments, and returns the arguments
+func parseInterleavedFlags(flagSet *flag.FlagSet, tokenList []string) ([]string, error) {
+	var argumentList []string
+	for {
+		if err := flagSet.Parse(tokenList); err != nil {
+			return nil, err
+		}
+		if flagSet.NArg() == 0 {
+			return argumentList, nil
+		}
+		argumentList = append(argumentList, flagSet.Arg(0))
+		tokenList = flagSet.Args()[1:]
+	}
+}
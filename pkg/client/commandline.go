@@ -0,0 +1,209 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+/******************
+ ** Command Line **
+ ******************/
+
+// SERVE_COMMAND runs the cluster without the REPL, only serving the commands of the command line
+const SERVE_COMMAND = "serve"
+
+const NO_CLUSTER_MESSAGE = "No running cluster ! Run `job_scheduler serve` in this directory first."
+const COMMAND_LINE_USAGE = "The command line must have the following form: `job_scheduler [--output (table|json|yaml)] <command> [arguments]`. For example: 'job_scheduler --output json status 1-2'"
+
+// commandLineRequest is a one-shot command sent by the command line to the cluster
+type commandLineRequest struct {
+	TokenList []string
+	// Directory of the relative paths given to the command
+	WorkingDirectory string
+	OutputFormat     OutputFormat
+}
+
+// commandLineFrame is a part of the outputs of a command sent back to the command line, or its exit
+// code once the command has ended
+type commandLineFrame struct {
+	Stderr   bool   `json:",omitempty"`
+	Data     []byte `json:",omitempty"`
+	Ended    bool   `json:",omitempty"`
+	ExitCode int    `json:",omitempty"`
+}
+
+// frameWriter sends what a command writes on one of its outputs to the command line
+type frameWriter struct {
+	encoder *json.Encoder
+	// Shared by the two outputs of a command
+	mutex  *sync.Mutex
+	stderr bool
+}
+
+// Write sends data to the command line as a frame
+func (writer frameWriter) Write(data []byte) (int, error) {
+	writer.mutex.Lock()
+	defer writer.mutex.Unlock()
+	if err := writer.encoder.Encode(commandLineFrame{Stderr: writer.stderr, Data: data}); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// parseCommandLineOptions parses the options given before the command and returns the command
+func parseCommandLineOptions(tokenList []string) ([]string, OutputFormat, error) {
+	format := TABLE_FORMAT
+	for len(tokenList) > 0 && strings.HasPrefix(tokenList[0], "-") {
+		name, value, hasValue := strings.Cut(tokenList[0], "=")
+		if name != "--output" && name != "-o" {
+			return nil, format, fmt.Errorf("Invalid option: %s", name)
+		}
+		tokenList = tokenList[1:]
+		if !hasValue {
+			if len(tokenList) == 0 {
+				return nil, format, fmt.Errorf("Missing value of option %s", name)
+			}
+			value, tokenList = tokenList[0], tokenList[1:]
+		}
+		var err error
+		if format, err = ParseOutputFormat(value); err != nil {
+			return nil, format, err
+		}
+	}
+	if len(tokenList) == 0 {
+		return nil, format, errors.New("Missing command")
+	}
+	return tokenList, format, nil
+}
+
+// RunCommandLine runs a one-shot command on the cluster serving the control socket, prints its
+// outputs and returns its exit code (see EXIT_SUCCESS)
+func RunCommandLine(argumentList []string) int {
+	tokenList, format, err := parseCommandLineOptions(argumentList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, COMMAND_LINE_USAGE)
+		return EXIT_USAGE
+	}
+	if strings.ToUpper(tokenList[0]) == HELP_COMMAND.String() {
+		fmt.Println(COMMAND_LINE_USAGE)
+		fmt.Println(HELP_MESSAGE)
+		return EXIT_SUCCESS
+	}
+
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error: ", err)
+		return EXIT_ERROR
+	}
+	connection, err := net.Dial("unix", core.Config.ControlSocketPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, NO_CLUSTER_MESSAGE)
+		return EXIT_ERROR
+	}
+	defer connection.Close()
+
+	request := commandLineRequest{TokenList: tokenList, WorkingDirectory: workingDirectory, OutputFormat: format}
+	if err := json.NewEncoder(connection).Encode(request); err != nil {
+		fmt.Fprintln(os.Stderr, "Error: ", err)
+		return EXIT_ERROR
+	}
+	decoder := json.NewDecoder(connection)
+	for {
+		var frame commandLineFrame
+		if err := decoder.Decode(&frame); err != nil {
+			fmt.Fprintln(os.Stderr, "Error: ", "the connection to the cluster has been lost")
+			return EXIT_ERROR
+		}
+		if frame.Ended {
+			return frame.ExitCode
+		}
+		if frame.Stderr {
+			os.Stderr.Write(frame.Data)
+		} else {
+			os.Stdout.Write(frame.Data)
+		}
+	}
+}
+
+// ListenCommandLine opens the control socket on which the cluster serves the commands of the
+// command line. Only the user running the cluster can connect to it.
+func ListenCommandLine() (net.Listener, error) {
+	path := core.Config.ControlSocketPath
+	if connection, err := net.Dial("unix", path); err == nil {
+		connection.Close()
+		return nil, fmt.Errorf("A cluster is already running with the control socket %s", path)
+	}
+	// Remove the socket left by a cluster which has not been stopped properly
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ServeCommandLine runs the commands of the command line received on the control socket, one at a
+// time. Several clients can serve the same socket to run commands concurrently.
+func (client *ClientNode) ServeCommandLine(listener net.Listener) {
+	go client.ServeBlobs()
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			logger.Error("Control socket closed", zap.Error(err))
+			return
+		}
+		client.serveCommandLineConnection(connection)
+	}
+}
+
+// serveCommandLineConnection runs the command received on a connection of the control socket and
+// sends back its outputs and its exit code
+func (client *ClientNode) serveCommandLineConnection(connection net.Conn) {
+	defer connection.Close()
+	var request commandLineRequest
+	if err := json.NewDecoder(connection).Decode(&request); err != nil {
+		logger.Warn("Invalid command line request", zap.Error(err))
+		return
+	}
+	logger.Info("Run command of the command line",
+		zap.Uint32("clientId", client.Id),
+		zap.Strings("tokenList", request.TokenList),
+	)
+
+	encoder := json.NewEncoder(connection)
+	mutex := &sync.Mutex{}
+	client.out = frameWriter{encoder: encoder, mutex: mutex}
+	client.errOut = frameWriter{encoder: encoder, mutex: mutex, stderr: true}
+	client.workingDirectory = request.WorkingDirectory
+	client.outputFormat = request.OutputFormat
+	client.stop = func() {
+		encoder.Encode(commandLineFrame{Ended: true, ExitCode: EXIT_SUCCESS})
+		os.Exit(0)
+	}
+	exitCode := client.RunCommand(request.TokenList)
+	encoder.Encode(commandLineFrame{Ended: true, ExitCode: exitCode})
+}
+
+// resolveCommandPath resolves a relative path given to a command against the working directory of
+// the command line
+func (client *ClientNode) resolveCommandPath(path string) string {
+	if path == "" || client.workingDirectory == "" {
+		return path
+	}
+	return resolvePath(client.workingDirectory, path)
+}
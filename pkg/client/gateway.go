@@ -0,0 +1,394 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+/******************
+ ** HTTP Gateway **
+ ******************/
+
+// The HTTP gateway exposes the job queue as a REST API, so that jobs can be submitted and followed
+// without Go, for example with curl:
+//
+//	POST   /jobs          submit the job whose source is the body, with the content type
+//	                      application/octet-stream, or a tar archive of a multi-file job with the
+//	                      content type application/x-tar. The query parameters are
+//	                      the options of SUBMIT (priority, after, arg, env...) and `at` or `cron` to
+//	                      create a schedule. Returns 201 and a SubmitOutput.
+//	GET    /jobs          list the jobs, with the options of STATUS as query parameters (state,
+//	                      label, sort, page...). Returns a ClusterStatusOutput.
+//	GET    /jobs/{ref}    get a job with its details. Returns a JobOutput.
+//	DELETE /jobs/{ref}    cancel a job. Returns 204.
+//	GET    /cluster       get the nodes of the cluster and its leader. Returns a ClusterOutput.
+//
+// The requests are forwarded concurrently to the leader by a single client. The errors are returned
+// as an ErrorOutput with the status 400 for an invalid request, 413 for a job larger than
+// Config.MaxArchiveSize, 404 for an unknown job, 409 for a request rejected by the leader, 503 if
+// the cluster is not started or has no leader, and 504 if the request has timed out.
+//
+// Since the jobs are executed on the host, the gateway only accepts the requests sent to its own
+// address (against DNS rebinding) and without an Origin header, so that a web page can not submit a
+// job. The content types accepted by POST /jobs can not be sent by a form, and DELETE can not be
+// sent by a web page without a preflight request, which the gateway does not accept either.
+
+// Content types of the body of POST /jobs: the source of a single-file job, or a tar archive
+const (
+	GATEWAY_SOURCE_CONTENT_TYPE  = "application/octet-stream"
+	GATEWAY_ARCHIVE_CONTENT_TYPE = "application/x-tar"
+)
+
+// errBodyTooLarge is returned when the body of POST /jobs is larger than Config.MaxArchiveSize
+var errBodyTooLarge = errors.New("The body of the request is too large")
+
+// ClusterOutput is the state of the cluster given by GET /cluster. The leader is omitted if the
+// cluster is not started.
+//
+//	{started: true, leader: {type: Scheduler, id: 2}, schedulers: 5, workers: 2, job_count: 12, schedules: [...]}
+type ClusterOutput struct {
+	Started    bool             `json:"started" yaml:"started"`
+	Leader     *NodeOutput      `json:"leader,omitempty" yaml:"leader,omitempty"`
+	Schedulers uint32           `json:"schedulers" yaml:"schedulers"`
+	Workers    uint32           `json:"workers" yaml:"workers"`
+	JobCount   uint32           `json:"job_count" yaml:"job_count"`
+	Schedules  []ScheduleOutput `json:"schedules" yaml:"schedules"`
+}
+
+// ErrorOutput is the body of the responses of the gateway to the requests which have failed
+//
+//	{error: "Job 1-2 does not exist."}
+type ErrorOutput struct {
+	Error string `json:"error" yaml:"error"`
+}
+
+// Gateway serves the HTTP API of the job queue with a client forwarding its requests to the leader
+type Gateway struct {
+	client *ClientNode
+	mux    *http.ServeMux
+	// Values of the Host header of the requests accepted by the gateway (see getAllowedHostMap)
+	allowedHostMap map[string]bool
+}
+
+// NewGateway creates a gateway forwarding the requests with a client created by NewClientNode, and
+// serves the blobs of the client
+func NewGateway(client *ClientNode) *Gateway {
+	gateway := &Gateway{
+		client: client,
+		mux:    http.NewServeMux(),
+	}
+	go client.ServeBlobs()
+	gateway.mux.HandleFunc("/jobs", gateway.handleJobs)
+	gateway.mux.HandleFunc("/jobs/", gateway.handleJob)
+	gateway.mux.HandleFunc("/cluster", gateway.handleCluster)
+	return gateway
+}
+
+// ListenGateway opens the TCP address on which the gateway serves the HTTP API
+func ListenGateway() (net.Listener, error) {
+	return net.Listen("tcp", core.Config.GatewayAddress)
+}
+
+// Serve serves the HTTP API on a listener until it is closed
+func (gateway *Gateway) Serve(listener net.Listener) {
+	gateway.allowedHostMap = getAllowedHostMap(listener)
+	server := &http.Server{Handler: gateway, ReadHeaderTimeout: 10 * time.Second}
+	if err := server.Serve(listener); err != nil {
+		logger.Error("HTTP gateway closed", zap.Error(err))
+	}
+}
+
+// getAllowedHostMap returns the values of the Host header accepted by the gateway: its address and,
+// if it listens on the loopback or on all the interfaces, the loopback names with its port. A
+// gateway reached from other hosts must have the name by which they reach it as address.
+func getAllowedHostMap(listener net.Listener) map[string]bool {
+	hostMap := map[string]bool{
+		core.Config.GatewayAddress: true,
+		listener.Addr().String():   true,
+	}
+	host, _, err := net.SplitHostPort(core.Config.GatewayAddress)
+	_, port, portErr := net.SplitHostPort(listener.Addr().String())
+	if err == nil && portErr == nil && (host == "" || host == "localhost" || net.ParseIP(host).IsLoopback()) {
+		for _, name := range []string{"localhost", "127.0.0.1", "::1"} {
+			hostMap[net.JoinHostPort(name, port)] = true
+		}
+	}
+	return hostMap
+}
+
+// ServeHTTP logs, checks and routes a request of the HTTP API
+func (gateway *Gateway) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	logger.Info("Handle HTTP request",
+		zap.String("method", request.Method),
+		zap.String("url", request.URL.String()),
+		zap.String("host", request.Host),
+	)
+	if !gateway.allowedHostMap[request.Host] {
+		writeError(writer, http.StatusForbidden, fmt.Errorf("Invalid host: %s", request.Host))
+		return
+	}
+	if request.Header.Get("Origin") != "" {
+		writeError(writer, http.StatusForbidden, errors.New("The requests sent by web pages are not accepted"))
+		return
+	}
+	gateway.mux.ServeHTTP(writer, request)
+}
+
+// handleJobs handles the requests on the collection of jobs
+func (gateway *Gateway) handleJobs(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		gateway.listJobs(writer, request)
+	case http.MethodPost:
+		gateway.submitJob(writer, request)
+	default:
+		writeMethodNotAllowed(writer, http.MethodGet, http.MethodPost)
+	}
+}
+
+// handleJob handles the requests on a job
+func (gateway *Gateway) handleJob(writer http.ResponseWriter, request *http.Request) {
+	reference := strings.TrimPrefix(request.URL.Path, "/jobs/")
+	if _, err := core.ParseJobReference(reference); err != nil {
+		writeError(writer, http.StatusNotFound, errors.New(INVALID_JOB_REFERENCE_MESSAGE))
+		return
+	}
+	switch request.Method {
+	case http.MethodGet:
+		gateway.getJob(writer, request, reference)
+	case http.MethodDelete:
+		gateway.cancelJob(writer, request, reference)
+	default:
+		writeMethodNotAllowed(writer, http.MethodGet, http.MethodDelete)
+	}
+}
+
+// submitJob handles POST /jobs
+func (gateway *Gateway) submitJob(writer http.ResponseWriter, request *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if contentType != GATEWAY_SOURCE_CONTENT_TYPE && contentType != GATEWAY_ARCHIVE_CONTENT_TYPE {
+		writeError(writer, http.StatusUnsupportedMediaType, fmt.Errorf("The content type must be %s for a source or %s for a tar archive",
+			GATEWAY_SOURCE_CONTENT_TYPE, GATEWAY_ARCHIVE_CONTENT_TYPE))
+		return
+	}
+	spec, err := parseGatewayJobSpec(request, contentType, time.Now())
+	if errors.Is(err, errBodyTooLarge) {
+		writeError(writer, http.StatusRequestEntityTooLarge, fmt.Errorf("The files of the job can not be larger than %d bytes", core.Config.MaxArchiveSize))
+		return
+	} else if err != nil {
+		writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	reference, err := gateway.client.Submit(request.Context(), spec)
+	if err != nil {
+		writeClientError(writer, err)
+		return
+	}
+	if spec.IsSchedule() {
+		writeOutput(writer, http.StatusCreated, SubmitOutput{Schedule: reference})
+		return
+	}
+	writer.Header().Set("Location", "/jobs/"+reference)
+	writeOutput(writer, http.StatusCreated, SubmitOutput{Reference: reference})
+}
+
+// parseGatewayJobSpec builds the job submitted by POST /jobs from its body and its query parameters
+func parseGatewayJobSpec(request *http.Request, contentType string, now time.Time) (JobSpec, error) {
+	var spec JobSpec
+	flagSet := newCommandFlagSet(SUBMIT_COMMAND)
+	options := newJobOptions()
+	options.register(flagSet)
+	for name, valueList := range request.URL.Query() {
+		for _, value := range valueList {
+			var err error
+			switch name {
+			case "at":
+				spec.StartAt, err = parseStartTime(value, now)
+			case "cron":
+				spec.Cron = value
+			case "stdin":
+				err = errors.New("The option stdin is not supported by the HTTP gateway")
+			default:
+				if err = flagSet.Set(name, value); err != nil {
+					err = fmt.Errorf("Invalid option %s: %v", name, err)
+				}
+			}
+			if err != nil {
+				return spec, err
+			}
+		}
+	}
+	if spec.Cron != "" && !spec.StartAt.IsZero() {
+		return spec, errors.New("The options at and cron can not be given together")
+	}
+	if spec.Cron != "" {
+		if _, err := core.ParseCronExpression(spec.Cron); err != nil {
+			return spec, err
+		}
+	}
+	if err := options.apply(&spec.Job); err != nil {
+		return spec, err
+	}
+
+	content, err := io.ReadAll(io.LimitReader(request.Body, int64(core.Config.MaxArchiveSize)+1))
+	if err != nil {
+		return spec, err
+	}
+	if len(content) == 0 {
+		return spec, errors.New("The body must contain the source of the job")
+	}
+	// The body is read up to one byte more than the limit to detect a larger body
+	if len(content) > int(core.Config.MaxArchiveSize) {
+		return spec, errBodyTooLarge
+	}
+	if contentType == GATEWAY_ARCHIVE_CONTENT_TYPE {
+		spec.Job.Archive = content
+	} else {
+		spec.Job.Input = string(content)
+	}
+	return spec, nil
+}
+
+// listJobs handles GET /jobs
+func (gateway *Gateway) listJobs(writer http.ResponseWriter, request *http.Request) {
+	flagSet := newCommandFlagSet(STATUS_COMMAND)
+	options := statusOptions{}
+	options.register(flagSet)
+	if err := setFlagsFromQuery(flagSet, request); err != nil {
+		writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+	query, err := options.buildJobQuery(time.Now())
+	if err != nil {
+		writeError(writer, http.StatusBadRequest, err)
+		return
+	}
+
+	jobList, err := gateway.client.ListJobs(request.Context(), query)
+	if err != nil {
+		writeClientError(writer, err)
+		return
+	}
+	writeOutput(writer, http.StatusOK, newClusterStatusOutput(query, jobList))
+}
+
+// getJob handles GET /jobs/{ref}
+func (gateway *Gateway) getJob(writer http.ResponseWriter, request *http.Request, reference Ref) {
+	job, jobMap, err := gateway.client.Status(request.Context(), reference)
+	if err != nil {
+		writeClientError(writer, err)
+		return
+	}
+	if err := job.LoadBlobs(gateway.client.blobStore, []core.BlobField{core.CompileLogBlobField, core.StdoutBlobField, core.StderrBlobField}); err != nil {
+		logger.Warn("Outputs of the job not loaded", zap.String("job", reference), zap.Error(err))
+	}
+	writeOutput(writer, http.StatusOK, newJobOutput(job, jobMap))
+}
+
+// cancelJob handles DELETE /jobs/{ref}
+func (gateway *Gateway) cancelJob(writer http.ResponseWriter, request *http.Request, reference Ref) {
+	// An unknown job is not found, and a job which has already ended is in conflict
+	if err := gateway.client.Cancel(request.Context(), reference); err != nil {
+		writeClientError(writer, err)
+		return
+	}
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// handleCluster handles GET /cluster
+func (gateway *Gateway) handleCluster(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		writeMethodNotAllowed(writer, http.MethodGet)
+		return
+	}
+	output := ClusterOutput{
+		Started:    isClusterStarted(),
+		Schedulers: core.Config.SchedulerNodeCount,
+		Workers:    core.Config.WorkerNodeCount,
+		Schedules:  []ScheduleOutput{},
+	}
+	if output.Started {
+		query := core.JobQuery{Limit: 1}
+		jobList, err := gateway.client.ListJobs(request.Context(), query)
+		if err != nil {
+			writeClientError(writer, err)
+			return
+		}
+		status := newClusterStatusOutput(query, jobList)
+		output.Leader = &NodeOutput{Type: core.SchedulerNodeType.String(), Id: atomic.LoadUint32(&gateway.client.LastLeaderId)}
+		output.JobCount = status.JobCount
+		output.Schedules = status.Schedules
+	}
+	writeOutput(writer, http.StatusOK, output)
+}
+
+// setFlagsFromQuery sets the options of a command from the query parameters of a request
+func setFlagsFromQuery(flagSet *flag.FlagSet, request *http.Request) error {
+	for name, valueList := range request.URL.Query() {
+		for _, value := range valueList {
+			if err := flagSet.Set(name, value); err != nil {
+				return fmt.Errorf("Invalid option %s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// writeOutput writes the body of a response in json
+func writeOutput(writer http.ResponseWriter, statusCode int, output interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(statusCode)
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(output); err != nil {
+		logger.Warn("HTTP response not sent", zap.Error(err))
+	}
+}
+
+// writeError writes the error of a request which has failed
+func writeError(writer http.ResponseWriter, statusCode int, err error) {
+	writeOutput(writer, statusCode, ErrorOutput{Error: err.Error()})
+}
+
+// writeClientError writes the error returned by the client API with the matching status code
+func writeClientError(writer http.ResponseWriter, err error) {
+	var rejectedError *RejectedError
+	switch {
+	case errors.Is(err, ErrClusterNotStarted):
+		writeError(writer, http.StatusServiceUnavailable, errors.New(NOT_STARTED_MESSAGE))
+	case errors.Is(err, ErrNoLeader):
+		writer.Header().Set("Retry-After", "1")
+		writeError(writer, http.StatusServiceUnavailable, err)
+	case errors.Is(err, ErrJobNotFound):
+		writeError(writer, http.StatusNotFound, errors.New(INVALID_JOB_REFERENCE_MESSAGE))
+	case errors.As(err, &rejectedError):
+		writeError(writer, http.StatusConflict, err)
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(writer, http.StatusGatewayTimeout, err)
+	case errors.Is(err, context.Canceled):
+		// The connection has been closed by the HTTP client, nobody reads the response
+		logger.Info("HTTP request cancelled")
+	default:
+		writeError(writer, http.StatusInternalServerError, err)
+	}
+}
+
+// writeMethodNotAllowed writes the error of a request whose method is not served on its path
+func writeMethodNotAllowed(writer http.ResponseWriter, methodList ...string) {
+	writer.Header().Set("Allow", strings.Join(methodList, ", "))
+	writeError(writer, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+}
@@ -0,0 +1,246 @@
+package client
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+// Extensions of the job manifest files. A job file with another extension is a source file.
+var jobManifestExtensionList = []string{".yaml", ".yml", ".json"}
+
+/******************
+ ** Job Manifest **
+ ******************/
+
+// JobManifestLimits are the limits of the resources used by a job
+type JobManifestLimits struct {
+	Timeout time.Duration `yaml:"timeout"`
+	CPU     time.Duration `yaml:"cpu"`
+	Memory  string        `yaml:"memory"`
+	CPUs    float64       `yaml:"cpus"`
+}
+
+// JobManifestRetries is the retry policy of a job
+type JobManifestRetries struct {
+	MaxAttempts uint          `yaml:"max_attempts"`
+	Backoff     string        `yaml:"backoff"`
+	Delay       time.Duration `yaml:"delay"`
+	OnExitCodes []int         `yaml:"on_exit_codes"`
+	OnTimeout   bool          `yaml:"on_timeout"`
+	OnSignal    bool          `yaml:"on_signal"`
+}
+
+// JobManifest describes a job in a YAML or JSON file of the following form:
+//
+//	sources: [job-medium-prime.cpp]
+//	language: c++
+//	compiler: g++
+//	compiler_flags: [-O2, -std=c++20]
+//	build: make
+//	executable: job.out
+//	args: ["100000"]
+//	env: {VERBOSE: "1"}
+//	stdin: input.txt
+//	artifacts: [results/*.csv, plot.png]
+//	limits: {timeout: 30s, cpu: 10s, memory: 256M, cpus: 2}
+//	priority: high
+//	retries: {max_attempts: 3, backoff: exponential, delay: 1s, on_exit_codes: [3]}
+//	labels: {team: benchmark}
+//
+// Only the sources are required. The paths are relative to the manifest file. A job with several
+// sources, a directory or a build command is a multi-file job and its sources must be in the
+// directory of the manifest, or in the directory given as the only source.
+type JobManifest struct {
+	Sources       []string           `yaml:"sources"`
+	Language      string             `yaml:"language"`
+	Compiler      string             `yaml:"compiler"`
+	CompilerFlags []string           `yaml:"compiler_flags"`
+	Build         string             `yaml:"build"`
+	Executable    string             `yaml:"executable"`
+	Args          []string           `yaml:"args"`
+	Env           map[string]string  `yaml:"env"`
+	Stdin         string             `yaml:"stdin"`
+	Artifacts     []string           `yaml:"artifacts"`
+	Limits        JobManifestLimits  `yaml:"limits"`
+	Priority      string             `yaml:"priority"`
+	After         []string           `yaml:"after"`
+	Array         string             `yaml:"array"`
+	Retries       JobManifestRetries `yaml:"retries"`
+	Labels        map[string]string  `yaml:"labels"`
+
+	// Directory of the manifest file
+	directory string
+}
+
+// IsJobManifestFile checks if a job file is a job manifest from its extension
+func IsJobManifestFile(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	for _, manifestExtension := range jobManifestExtensionList {
+		if extension == manifestExtension {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadJobManifestFromFile loads a job manifest. Unknown fields are rejected.
+func LoadJobManifestFromFile(path string) (*JobManifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest JobManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("Invalid job manifest: %v", err)
+	}
+	if len(manifest.Sources) == 0 {
+		return nil, fmt.Errorf("The job manifest does not contain any source file")
+	}
+
+	directory := filepath.Dir(path)
+	manifest.directory = directory
+	for i := range manifest.Sources {
+		manifest.Sources[i] = resolvePath(directory, manifest.Sources[i])
+	}
+	if manifest.Stdin != "" {
+		manifest.Stdin = resolvePath(directory, manifest.Stdin)
+	}
+	return &manifest, nil
+}
+
+// resolvePath returns a path relative to a directory, unless it is absolute
+func resolvePath(directory string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(directory, path)
+}
+
+// mergeInto sets the fields of the manifest on the options, except the options in setOptionMap
+// which have been given on the command line
+func (manifest *JobManifest) mergeInto(options *jobOptions, setOptionMap map[string]bool) {
+	mergeOption := func(name string, isSet bool, merge func()) {
+		if isSet && !setOptionMap[name] {
+			merge()
+		}
+	}
+	mergeOption("priority", manifest.Priority != "", func() { options.priority = manifest.Priority })
+	mergeOption("after", len(manifest.After) > 0, func() { options.after = strings.Join(manifest.After, ",") })
+	mergeOption("array", manifest.Array != "", func() { options.array = manifest.Array })
+	mergeOption("label", len(manifest.Labels) > 0, func() { options.labels = formatKeyValueMap(manifest.Labels) })
+
+	mergeOption("language", manifest.Language != "", func() { options.language = manifest.Language })
+	mergeOption("compiler", manifest.Compiler != "", func() { options.compiler = manifest.Compiler })
+	mergeOption("compiler-flag", len(manifest.CompilerFlags) > 0, func() { options.compilerFlags = manifest.CompilerFlags })
+	mergeOption("build", manifest.Build != "", func() { options.build = manifest.Build })
+	mergeOption("executable", manifest.Executable != "", func() { options.executable = manifest.Executable })
+
+	mergeOption("arg", len(manifest.Args) > 0, func() { options.args = manifest.Args })
+	mergeOption("env", len(manifest.Env) > 0, func() { options.env = formatKeyValueMap(manifest.Env) })
+	mergeOption("stdin", manifest.Stdin != "", func() { options.stdinFile = manifest.Stdin })
+	mergeOption("artifact", len(manifest.Artifacts) > 0, func() { options.artifacts = manifest.Artifacts })
+
+	mergeOption("timeout", manifest.Limits.Timeout != 0, func() { options.timeout = manifest.Limits.Timeout })
+	mergeOption("cpu-limit", manifest.Limits.CPU != 0, func() { options.cpuLimit = manifest.Limits.CPU })
+	mergeOption("memory-limit", manifest.Limits.Memory != "", func() { options.memoryLimit = manifest.Limits.Memory })
+	mergeOption("cpus", manifest.Limits.CPUs != 0, func() { options.cpus = manifest.Limits.CPUs })
+
+	retries := manifest.Retries
+	mergeOption("max-attempts", retries.MaxAttempts != 0, func() { options.maxAttempts = retries.MaxAttempts })
+	mergeOption("backoff", retries.Backoff != "", func() { options.backoff = retries.Backoff })
+	mergeOption("retry-delay", retries.Delay != 0, func() { options.retryDelay = retries.Delay })
+	mergeOption("retry-on", len(retries.OnExitCodes) > 0, func() { options.retryOn = retries.OnExitCodes })
+	mergeOption("retry-on-timeout", retries.OnTimeout, func() { options.retryOnTimeout = retries.OnTimeout })
+	mergeOption("retry-on-signal", retries.OnSignal, func() { options.retryOnSignal = retries.OnSignal })
+}
+
+// loadSources loads the sources of the manifest into a job: as the input of a single-file job, or
+// as the archive of a multi-file job
+func (manifest *JobManifest) loadSources(job *core.Job) error {
+	isMultiFile := len(manifest.Sources) > 1 || job.BuildCommand != ""
+	for _, sourcePath := range manifest.Sources {
+		if info, err := os.Stat(sourcePath); err != nil {
+			return err
+		} else if info.IsDir() {
+			isMultiFile = true
+		}
+	}
+
+	var err error
+	if len(manifest.Sources) == 1 && isDirectory(manifest.Sources[0]) {
+		job.Archive, err = core.CreateArchive(manifest.Sources[0], manifest.Sources)
+	} else if isMultiFile {
+		job.Archive, err = core.CreateArchive(manifest.directory, manifest.Sources)
+	} else {
+		job.Input, err = core.LoadCodeFromFile(manifest.Sources[0])
+	}
+	return err
+}
+
+/**************
+ ** Job File **
+ **************/
+
+// loadJobFromFile builds a job from options and from a job file, which is either a source file, a
+// directory or a tar archive of a multi-file job, or a job manifest. The options given on the
+// command line (see getSetOptionMap) override the fields of the manifest.
+func loadJobFromFile(path string, options jobOptions, setOptionMap map[string]bool) (core.Job, error) {
+	job := core.Job{WorkerId: core.NO_WORKER}
+	var manifest *JobManifest
+	if IsJobManifestFile(path) {
+		var err error
+		if manifest, err = LoadJobManifestFromFile(path); err != nil {
+			return job, err
+		}
+		manifest.mergeInto(&options, setOptionMap)
+	}
+
+	if err := options.apply(&job); err != nil {
+		return job, err
+	}
+
+	var err error
+	switch {
+	case manifest != nil:
+		err = manifest.loadSources(&job)
+	case core.IsArchiveFile(path):
+		job.Archive, err = ioutil.ReadFile(path)
+	case isDirectory(path):
+		job.Archive, err = core.CreateArchive(path, []string{path})
+	default:
+		job.Input, err = core.LoadCodeFromFile(path)
+	}
+	if err != nil {
+		return job, fmt.Errorf("Error while loading job file : %v", err)
+	}
+	if len(job.Archive) > int(core.Config.MaxArchiveSize) {
+		return job, fmt.Errorf("The files of the job can not be larger than %d bytes", core.Config.MaxArchiveSize)
+	}
+	return job, nil
+}
+
+// isDirectory checks if a path is a directory
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// getSetOptionMap returns the names of the options given on the command line
+func getSetOptionMap(flagSet *flag.FlagSet) map[string]bool {
+	setOptionMap := make(map[string]bool)
+	flagSet.Visit(func(option *flag.Flag) {
+		setOptionMap[option.Name] = true
+	})
+	return setOptionMap
+}
@@ -0,0 +1,262 @@
+package client
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/**********************
+ ** String List Flag **
+ **********************/
+
+// stringListFlag is a flag which can be repeated to give several values
+type stringListFlag []string
+
+func (list *stringListFlag) String() string {
+	return strings.Join(*list, " ")
+}
+
+func (list *stringListFlag) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+/*************************
+ ** Exit Code List Flag **
+ *************************/
+
+// exitCodeListFlag is a flag giving a comma separated list of exit codes
+type exitCodeListFlag []int
+
+func (list *exitCodeListFlag) String() string {
+	tokenList := make([]string, 0, len(*list))
+	for _, exitCode := range *list {
+		tokenList = append(tokenList, strconv.Itoa(exitCode))
+	}
+	return strings.Join(tokenList, ",")
+}
+
+func (list *exitCodeListFlag) Set(value string) error {
+	*list = nil
+	for _, exitCodeToken := range splitCommaSeparatedList(value) {
+		exitCode, err := strconv.Atoi(exitCodeToken)
+		if err != nil {
+			return fmt.Errorf("Invalid exit code: %s", exitCodeToken)
+		}
+		*list = append(*list, exitCode)
+	}
+	return nil
+}
+
+/*****************
+ ** Job Options **
+ *****************/
+
+// jobOptions contains the options of the SUBMIT command used to build a job
+type jobOptions struct {
+	priority string
+	after    string
+	array    string
+	labels   stringListFlag
+
+	language      string
+	compiler      string
+	compilerFlags stringListFlag
+	build         string
+	executable    string
+
+	args      stringListFlag
+	env       stringListFlag
+	stdinFile string
+	artifacts stringListFlag
+
+	timeout     time.Duration
+	cpuLimit    time.Duration
+	memoryLimit string
+	cpus        float64
+
+	maxAttempts    uint
+	backoff        string
+	retryDelay     time.Duration
+	retryOn        exitCodeListFlag
+	retryOnTimeout bool
+	retryOnSignal  bool
+}
+
+// newJobOptions returns the options of a job with their default values
+func newJobOptions() jobOptions {
+	return jobOptions{
+		priority:    core.MediumPriority.String(),
+		language:    core.DEFAULT_LANGUAGE,
+		compiler:    core.DEFAULT_COMPILER,
+		maxAttempts: 1,
+		backoff:     core.FixedBackoff.String(),
+		retryDelay:  time.Second,
+	}
+}
+
+// register adds the job options to the flag set of a command. The current values of the options are the default values of the flags.
+func (options *jobOptions) register(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&options.priority, "priority", options.priority, "priority of the job")
+	flagSet.StringVar(&options.after, "after", options.after, "references of the jobs which must succeed before this job")
+	flagSet.StringVar(&options.array, "array", options.array, "range of indexes of a job array")
+	flagSet.Var(&options.labels, "label", "label KEY=VALUE describing the job (repeatable)")
+
+	flagSet.StringVar(&options.language, "language", options.language, "language of the job")
+	flagSet.StringVar(&options.compiler, "compiler", options.compiler, "compiler of the job")
+	flagSet.Var(&options.compilerFlags, "compiler-flag", "flag given to the compiler (repeatable)")
+	flagSet.StringVar(&options.build, "build", options.build, "command building a multi-file job")
+	flagSet.StringVar(&options.executable, "executable", options.executable, "executable built by a multi-file job")
+
+	flagSet.Var(&options.args, "arg", "argument given to the job (repeatable)")
+	flagSet.Var(&options.env, "env", "environment variable KEY=VALUE given to the job (repeatable)")
+	flagSet.StringVar(&options.stdinFile, "stdin", options.stdinFile, "file given to the job on its standard input")
+	flagSet.Var(&options.artifacts, "artifact", "pattern of the files collected after the execution of the job (repeatable)")
+
+	flagSet.DurationVar(&options.timeout, "timeout", options.timeout, "maximum duration of the execution of the job")
+	flagSet.DurationVar(&options.cpuLimit, "cpu-limit", options.cpuLimit, "maximum CPU time used by the job")
+	flagSet.StringVar(&options.memoryLimit, "memory-limit", options.memoryLimit, "maximum memory used by the job")
+	flagSet.Float64Var(&options.cpus, "cpus", options.cpus, "number of CPUs used by the job")
+
+	flagSet.UintVar(&options.maxAttempts, "max-attempts", options.maxAttempts, "maximum number of executions of the job")
+	flagSet.StringVar(&options.backoff, "backoff", options.backoff, "backoff between two attempts")
+	flagSet.DurationVar(&options.retryDelay, "retry-delay", options.retryDelay, "delay before the first retry")
+	flagSet.Var(&options.retryOn, "retry-on", "exit codes for which the job is retried")
+	flagSet.BoolVar(&options.retryOnTimeout, "retry-on-timeout", options.retryOnTimeout, "retry the job when it exceeds its timeout")
+	flagSet.BoolVar(&options.retryOnSignal, "retry-on-signal", options.retryOnSignal, "retry the job when it is killed by a signal")
+}
+
+// apply checks the options and sets them on a job
+func (options *jobOptions) apply(job *core.Job) error {
+	priority, err := core.ParseJobPriority(options.priority)
+	if err != nil {
+		return err
+	}
+	backoff, err := core.ParseBackoffType(options.backoff)
+	if err != nil {
+		return err
+	}
+	for _, exitCode := range options.retryOn {
+		if exitCode <= 0 || exitCode > 255 {
+			return fmt.Errorf("Invalid exit code: %d", exitCode)
+		}
+	}
+	if options.maxAttempts == 0 {
+		return fmt.Errorf("The maximum number of attempts must be at least 1")
+	}
+	if options.timeout < 0 || options.cpuLimit < 0 || options.retryDelay < 0 {
+		return fmt.Errorf("Durations must be positive")
+	}
+	if options.cpus < 0 {
+		return fmt.Errorf("The number of CPUs must be positive")
+	}
+	if !core.IsValidLanguage(options.language) {
+		return fmt.Errorf("Invalid language: %s. It must be one of: %s", options.language, strings.Join(core.LanguageList, ", "))
+	}
+	if options.executable != "" {
+		executable := filepath.Clean(options.executable)
+		if filepath.IsAbs(executable) || executable == ".." || strings.HasPrefix(executable, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("Invalid executable: %s. It must be a path relative to the files of the job", options.executable)
+		}
+	}
+	for _, pattern := range options.artifacts {
+		if err := core.CheckArtifactPattern(pattern); err != nil {
+			return err
+		}
+	}
+	if options.array != "" {
+		if job.ArrayStart, job.ArraySize, err = parseArrayRange(options.array); err != nil {
+			return err
+		}
+	}
+	if options.memoryLimit != "" {
+		if job.MemoryLimit, err = core.ParseMemorySize(options.memoryLimit); err != nil {
+			return err
+		}
+	}
+	if job.Labels, err = parseKeyValueList(options.labels, "label"); err != nil {
+		return err
+	}
+	if job.Env, err = parseKeyValueList(options.env, "environment variable"); err != nil {
+		return err
+	}
+	if options.stdinFile != "" {
+		if job.Stdin, err = core.LoadCodeFromFile(options.stdinFile); err != nil {
+			return fmt.Errorf("Error while loading stdin file : %v", err)
+		}
+	}
+
+	job.Priority = priority
+	job.Dependencies = splitCommaSeparatedList(options.after)
+	job.Language = options.language
+	job.Compiler = options.compiler
+	job.CompilerFlags = options.compilerFlags
+	// The compiler options are checked again by the workers, but the submission fails earlier here
+	if err := job.CheckCompilerOptions(); err != nil {
+		return err
+	}
+	job.BuildCommand = options.build
+	job.Executable = options.executable
+	job.Args = options.args
+	job.ArtifactPatterns = options.artifacts
+	job.Timeout = options.timeout
+	job.CPULimit = options.cpuLimit
+	job.CPUQuota = options.cpus
+	job.RetryPolicy = core.RetryPolicy{
+		MaxAttempts:      uint32(options.maxAttempts),
+		Backoff:          backoff,
+		Delay:            options.retryDelay,
+		RetryOnExitCodes: options.retryOn,
+		RetryOnTimeout:   options.retryOnTimeout,
+		RetryOnSignal:    options.retryOnSignal,
+	}
+	return nil
+}
+
+// parseArrayRange parses the range `<first index>-<last index>` of a job array
+func parseArrayRange(token string) (int, uint32, error) {
+	startToken, endToken, ok := strings.Cut(token, "-")
+	start, startErr := strconv.Atoi(startToken)
+	end, endErr := strconv.Atoi(endToken)
+	if !ok || startErr != nil || endErr != nil || start < 0 || end < start {
+		return 0, 0, fmt.Errorf("Invalid array range: %s", token)
+	}
+	size := end - start + 1
+	if size > int(core.Config.MaxArraySize) {
+		return 0, 0, fmt.Errorf("A job array can not contain more than %d jobs", core.Config.MaxArraySize)
+	}
+	return start, uint32(size), nil
+}
+
+// parseKeyValueList parses a list of environment variables or labels of the form `KEY=VALUE`
+func parseKeyValueList(keyValueList []string, name string) (map[string]string, error) {
+	if len(keyValueList) == 0 {
+		return nil, nil
+	}
+	keyValueMap := make(map[string]string)
+	for _, keyValue := range keyValueList {
+		key, value, ok := strings.Cut(keyValue, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("Invalid %s: %s. It must have the form KEY=VALUE", name, keyValue)
+		}
+		keyValueMap[key] = value
+	}
+	return keyValueMap, nil
+}
+
+// formatKeyValueMap converts a map of environment variables or labels to a list of `KEY=VALUE` sorted by key
+func formatKeyValueMap(keyValueMap map[string]string) []string {
+	keyValueList := make([]string, 0, len(keyValueMap))
+	for key, value := range keyValueMap {
+		keyValueList = append(keyValueList, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(keyValueList)
+	return keyValueList
+}
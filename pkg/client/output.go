@@ -0,0 +1,413 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"gopkg.in/yaml.v3"
+)
+
+/*******************
+ ** Output Format **
+ *******************/
+
+// OutputFormat is the format in which the commands print the jobs and the status of the cluster
+type OutputFormat string
+
+const (
+	TABLE_FORMAT OutputFormat = "table"
+	JSON_FORMAT  OutputFormat = "json"
+	YAML_FORMAT  OutputFormat = "yaml"
+)
+
+// Convert an OutputFormat to a string
+func (f OutputFormat) String() string {
+	return string(f)
+}
+
+// ParseOutputFormat converts a string (table, json or yaml) to an OutputFormat
+func ParseOutputFormat(token string) (OutputFormat, error) {
+	for _, format := range []OutputFormat{TABLE_FORMAT, JSON_FORMAT, YAML_FORMAT} {
+		if strings.ToLower(token) == format.String() {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("Invalid output format: %s", token)
+}
+
+// IsStructured checks if the format is read by programs: the commands then only print their
+// result on the standard output, and their errors on the standard error
+func (f OutputFormat) IsStructured() bool {
+	return f == JSON_FORMAT || f == YAML_FORMAT
+}
+
+// handleFormatCommand handles the format command to set the output format of the client
+func (client *ClientNode) handleFormatCommand(tokenList []string) {
+	if len(tokenList) != 2 {
+		client.printUsage(FORMAT_COMMAND_USAGE)
+		return
+	}
+	format, err := ParseOutputFormat(tokenList[1])
+	if err != nil {
+		client.fail(err)
+		client.printUsage(FORMAT_COMMAND_USAGE)
+		return
+	}
+	client.outputFormat = format
+	fmt.Fprintln(client.errorOut(), "Output format set to", format)
+}
+
+// printProgress prints what a command is doing, only in the table format
+func (client *ClientNode) printProgress(message ...interface{}) {
+	if !client.outputFormat.IsStructured() {
+		fmt.Fprint(client.out, message...)
+	}
+}
+
+// errorOut returns the output of the error messages, which is the standard error in the
+// structured formats
+func (client *ClientNode) errorOut() io.Writer {
+	if client.outputFormat.IsStructured() {
+		return client.errOut
+	}
+	return client.out
+}
+
+// printOutput prints the result of a command in the structured output format of the client
+func (client *ClientNode) printOutput(output interface{}) {
+	var err error
+	switch client.outputFormat {
+	case JSON_FORMAT:
+		encoder := json.NewEncoder(client.out)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(output)
+	case YAML_FORMAT:
+		encoder := yaml.NewEncoder(client.out)
+		encoder.SetIndent(2)
+		if err = encoder.Encode(output); err == nil {
+			err = encoder.Close()
+		}
+	}
+	if err != nil {
+		client.printError(err)
+	}
+}
+
+/********************
+ ** Output Objects **
+ ********************/
+
+// The structures below are printed by the commands in the json and yaml formats. Their fields are
+// stable: fields can be added but are never renamed or removed. The optional fields are omitted
+// when they are not set, the times follow RFC 3339 and the durations are Go durations such as
+// "1.5s".
+
+// NodeOutput identifies a node of the cluster
+//
+//	{type: Client, id: 0}
+type NodeOutput struct {
+	Type string `json:"type" yaml:"type"`
+	Id   uint32 `json:"id" yaml:"id"`
+}
+
+// JobArrayOutput is the progress of a job array
+//
+//	{size: 10, first_index: 1, ended: 4, states: {SUCCEEDED: 3, FAILED: 1, WAITING: 6}}
+type JobArrayOutput struct {
+	Size       uint32 `json:"size" yaml:"size"`
+	FirstIndex int    `json:"first_index,omitempty" yaml:"first_index,omitempty"`
+	Ended      uint32 `json:"ended" yaml:"ended"`
+	// Number of jobs in each state, only given with the details of the array
+	States map[string]uint32 `json:"states,omitempty" yaml:"states,omitempty"`
+}
+
+// ResourceUsageOutput is the resources used by an attempt
+//
+//	{wall_time: 1.2s, cpu_time: 1.1s, peak_memory: 2097152, cgroup: true}
+type ResourceUsageOutput struct {
+	WallTime   string `json:"wall_time" yaml:"wall_time"`
+	CPUTime    string `json:"cpu_time" yaml:"cpu_time"`
+	PeakMemory uint64 `json:"peak_memory,omitempty" yaml:"peak_memory,omitempty"`
+	Cgroup     bool   `json:"cgroup" yaml:"cgroup"`
+}
+
+// JobAttemptOutput is an attempt of a job
+//
+//	number: 1
+//	worker: 0
+//	started_at: 2022-12-01T10:00:00.123+01:00
+//	finished_at: 2022-12-01T10:00:01.456+01:00
+//	exit_code: 0
+//	signal: SIGKILL
+//	timed_out: false
+//	error: Compilation error
+//	cached_build: true
+//	workspace: /tmp/algorep/worker-0/1-2-1
+//	usage: {wall_time: 1.2s, cpu_time: 1.1s, cgroup: false}
+type JobAttemptOutput struct {
+	Number      uint32               `json:"number" yaml:"number"`
+	Worker      int                  `json:"worker" yaml:"worker"`
+	StartedAt   time.Time            `json:"started_at" yaml:"started_at"`
+	FinishedAt  time.Time            `json:"finished_at" yaml:"finished_at"`
+	ExitCode    *int                 `json:"exit_code,omitempty" yaml:"exit_code,omitempty"`
+	Signal      string               `json:"signal,omitempty" yaml:"signal,omitempty"`
+	TimedOut    bool                 `json:"timed_out" yaml:"timed_out"`
+	Error       string               `json:"error,omitempty" yaml:"error,omitempty"`
+	CachedBuild bool                 `json:"cached_build" yaml:"cached_build"`
+	Workspace   string               `json:"workspace,omitempty" yaml:"workspace,omitempty"`
+	Usage       *ResourceUsageOutput `json:"usage,omitempty" yaml:"usage,omitempty"`
+}
+
+// JobOutput is a job listed by STATUS or WAIT, or the details of a job given by STATUS <job reference>
+// and SUBMIT --wait, which also have the fields after attempt_count:
+//
+//	reference: 1-2
+//	state: SUCCEEDED
+//	worker: 0
+//	priority: MEDIUM
+//	submitted_at: 2022-12-01T10:00:00.123+01:00
+//	submitter: {type: Client, id: 0}
+//	labels: {team: benchmark}
+//	schedule: S1-2
+//	attempt_count: 1
+//	exit_code: 0
+//	signal: SIGKILL
+//	array: {size: 10, ended: 4}
+//	array_parent: 1-2
+//	array_index: 5
+//	after: [1-1]
+//	language: c++
+//	compiler: g++
+//	compiler_flags: [-O2]
+//	args: ["10"]
+//	env: {N: "10"}
+//	retry_policy: 3 attempts, exponential backoff of 1s
+//	attempts: [...]
+//	compile_log: ...
+//	stdout: ...
+//	stderr: ...
+//	artifacts: [results/out.csv]
+//	array_jobs: [...]
+//
+// The worker is -1 while the job is not assigned to a worker, and the exit code is omitted when
+// the job has not exited.
+type JobOutput struct {
+	Reference    string            `json:"reference" yaml:"reference"`
+	State        string            `json:"state" yaml:"state"`
+	Worker       int               `json:"worker" yaml:"worker"`
+	Priority     string            `json:"priority" yaml:"priority"`
+	SubmittedAt  time.Time         `json:"submitted_at" yaml:"submitted_at"`
+	Submitter    NodeOutput        `json:"submitter" yaml:"submitter"`
+	Labels       map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Schedule     string            `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	AttemptCount int               `json:"attempt_count" yaml:"attempt_count"`
+	ExitCode     *int              `json:"exit_code,omitempty" yaml:"exit_code,omitempty"`
+	Signal       string            `json:"signal,omitempty" yaml:"signal,omitempty"`
+	Array        *JobArrayOutput   `json:"array,omitempty" yaml:"array,omitempty"`
+	ArrayParent  string            `json:"array_parent,omitempty" yaml:"array_parent,omitempty"`
+	ArrayIndex   *int              `json:"array_index,omitempty" yaml:"array_index,omitempty"`
+
+	// Details of the job
+	After         []string           `json:"after,omitempty" yaml:"after,omitempty"`
+	Language      string             `json:"language,omitempty" yaml:"language,omitempty"`
+	Compiler      string             `json:"compiler,omitempty" yaml:"compiler,omitempty"`
+	CompilerFlags []string           `json:"compiler_flags,omitempty" yaml:"compiler_flags,omitempty"`
+	Args          []string           `json:"args,omitempty" yaml:"args,omitempty"`
+	Env           map[string]string  `json:"env,omitempty" yaml:"env,omitempty"`
+	RetryPolicy   string             `json:"retry_policy,omitempty" yaml:"retry_policy,omitempty"`
+	Attempts      []JobAttemptOutput `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+	CompileLog    string             `json:"compile_log,omitempty" yaml:"compile_log,omitempty"`
+	Stdout        string             `json:"stdout,omitempty" yaml:"stdout,omitempty"`
+	Stderr        string             `json:"stderr,omitempty" yaml:"stderr,omitempty"`
+	Artifacts     []string           `json:"artifacts,omitempty" yaml:"artifacts,omitempty"`
+	ArrayJobs     []JobOutput        `json:"array_jobs,omitempty" yaml:"array_jobs,omitempty"`
+}
+
+// ScheduleOutput is a schedule listed by STATUS. The cron expression is omitted for a delayed job.
+//
+//	{reference: S1-2, cron: "*/5 * * * *", next_run: 2022-12-01T10:05:00+01:00}
+type ScheduleOutput struct {
+	Reference string    `json:"reference" yaml:"reference"`
+	Cron      string    `json:"cron,omitempty" yaml:"cron,omitempty"`
+	NextRun   time.Time `json:"next_run" yaml:"next_run"`
+}
+
+// ClusterStatusOutput is the status of the cluster given by STATUS: a page of the jobs matching
+// the options, the number of matching jobs and the schedules
+//
+//	jobs: [...]
+//	job_count: 120
+//	page: 1
+//	page_count: 3
+//	schedules: [...]
+type ClusterStatusOutput struct {
+	Jobs      []JobOutput      `json:"jobs" yaml:"jobs"`
+	JobCount  uint32           `json:"job_count" yaml:"job_count"`
+	Page      uint32           `json:"page" yaml:"page"`
+	PageCount uint32           `json:"page_count" yaml:"page_count"`
+	Schedules []ScheduleOutput `json:"schedules" yaml:"schedules"`
+}
+
+// SubmitOutput is the reference of the job submitted by SUBMIT, or of the schedule created by
+// SUBMIT --at and SCHEDULE
+//
+//	{reference: 1-2}
+type SubmitOutput struct {
+	Reference string `json:"reference,omitempty" yaml:"reference,omitempty"`
+	Schedule  string `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+}
+
+// WorkflowJobOutput is a job of a workflow submitted by WORKFLOW
+//
+//	{name: prime, reference: 2-1, after: [hello]}
+type WorkflowJobOutput struct {
+	Name      string   `json:"name" yaml:"name"`
+	Reference string   `json:"reference" yaml:"reference"`
+	After     []string `json:"after" yaml:"after"`
+}
+
+// WaitOutput is the result of WAIT: the awaited jobs have all ended, or the timeout has passed, and
+// their state
+//
+//	{ended: true, jobs: [...]}
+type WaitOutput struct {
+	Ended bool        `json:"ended" yaml:"ended"`
+	Jobs  []JobOutput `json:"jobs" yaml:"jobs"`
+}
+
+// newJobSummaryOutput converts the summary of a job listed by the leader
+func newJobSummaryOutput(summary core.JobSummary) JobOutput {
+	output := JobOutput{
+		Reference:    summary.Reference,
+		State:        summary.State.String(),
+		Worker:       summary.WorkerId,
+		Priority:     summary.Priority.String(),
+		SubmittedAt:  summary.SubmittedAt,
+		Submitter:    NodeOutput{Type: summary.Submitter.Type.String(), Id: summary.Submitter.Id},
+		Labels:       summary.Labels,
+		Schedule:     summary.ScheduleReference,
+		AttemptCount: summary.AttemptCount,
+		Signal:       summary.Signal,
+	}
+	if summary.AttemptCount > 0 && summary.ExitCode != core.NO_EXIT_CODE {
+		exitCode := summary.ExitCode
+		output.ExitCode = &exitCode
+	}
+	if summary.IsArray() {
+		output.Array = &JobArrayOutput{Size: summary.ArraySize, Ended: summary.ArrayEndedCount}
+	}
+	return output
+}
+
+// newJobOutput converts the details of a job, with the jobs of a job array given in jobMap
+func newJobOutput(job core.Job, jobMap map[string]core.Job) JobOutput {
+	output := newJobSummaryOutput(job.GetSummary())
+	if job.IsArray() {
+		output.Array.FirstIndex = job.ArrayStart
+		output.Array.States = make(map[string]uint32)
+		for state, count := range job.ArrayStateCount {
+			output.Array.States[state.String()] = count
+		}
+		for index := job.ArrayStart; index < job.ArrayStart+int(job.ArraySize); index++ {
+			if child, ok := jobMap[job.GetArrayChildReference(index)]; ok {
+				output.ArrayJobs = append(output.ArrayJobs, newJobOutput(child, nil))
+			}
+		}
+	}
+	if job.IsArrayChild() {
+		output.ArrayParent = job.ArrayParent
+		arrayIndex := job.ArrayIndex
+		output.ArrayIndex = &arrayIndex
+	}
+	output.After = job.Dependencies
+	output.Language = job.GetLanguage()
+	output.Compiler = job.GetCompiler()
+	output.CompilerFlags = job.CompilerFlags
+	output.Args = job.Args
+	output.Env = job.Env
+	output.RetryPolicy = job.RetryPolicy.String()
+	for _, attempt := range job.Attempts {
+		output.Attempts = append(output.Attempts, newJobAttemptOutput(attempt))
+	}
+	output.CompileLog = job.CompileLog
+	output.Stdout = job.Stdout
+	output.Stderr = job.Stderr
+	output.Artifacts = job.ArtifactList
+	return output
+}
+
+// newJobAttemptOutput converts an attempt of a job
+func newJobAttemptOutput(attempt core.JobAttempt) JobAttemptOutput {
+	output := JobAttemptOutput{
+		Number:      attempt.Number,
+		Worker:      attempt.WorkerId,
+		StartedAt:   attempt.StartedAt,
+		FinishedAt:  attempt.FinishedAt,
+		Signal:      attempt.Signal,
+		TimedOut:    attempt.TimedOut,
+		Error:       attempt.Error,
+		CachedBuild: attempt.CompileCacheHit,
+		Workspace:   attempt.Workspace,
+	}
+	if attempt.ExitCode != core.NO_EXIT_CODE {
+		exitCode := attempt.ExitCode
+		output.ExitCode = &exitCode
+	}
+	if attempt.Usage.IsMeasured() {
+		output.Usage = &ResourceUsageOutput{
+			WallTime:   attempt.Usage.WallTime.String(),
+			CPUTime:    attempt.Usage.CPUTime.String(),
+			PeakMemory: attempt.Usage.PeakMemory,
+			Cgroup:     attempt.Usage.Cgroup,
+		}
+	}
+	return output
+}
+
+// newScheduleOutput converts a schedule
+func newScheduleOutput(reference string, schedule core.Schedule) ScheduleOutput {
+	output := ScheduleOutput{Reference: reference, NextRun: schedule.NextRun}
+	if schedule.IsRecurring() {
+		output.Cron = schedule.Cron
+	}
+	return output
+}
+
+// newClusterStatusOutput converts the status of the cluster listed by the leader for a query
+func newClusterStatusOutput(query core.JobQuery, jobList *JobList) ClusterStatusOutput {
+	limit := query.GetLimit()
+	output := ClusterStatusOutput{
+		Jobs:      make([]JobOutput, 0, len(jobList.Jobs)),
+		JobCount:  jobList.JobCount,
+		Page:      query.Offset/limit + 1,
+		PageCount: (jobList.JobCount + limit - 1) / limit,
+		Schedules: make([]ScheduleOutput, 0, len(jobList.Schedules)),
+	}
+	for _, summary := range jobList.Jobs {
+		output.Jobs = append(output.Jobs, newJobSummaryOutput(summary))
+	}
+	for reference, schedule := range jobList.Schedules {
+		output.Schedules = append(output.Schedules, newScheduleOutput(reference, schedule))
+	}
+	sort.Slice(output.Schedules, func(i, j int) bool {
+		return output.Schedules[i].Reference < output.Schedules[j].Reference
+	})
+	return output
+}
+
+// newWaitOutput converts the jobs awaited by WAIT, in the order of their references
+func newWaitOutput(jobMap map[string]core.Job, jobsEnded bool) WaitOutput {
+	output := WaitOutput{Ended: jobsEnded, Jobs: make([]JobOutput, 0, len(jobMap))}
+	for _, job := range jobMap {
+		output.Jobs = append(output.Jobs, newJobSummaryOutput(job.GetSummary()))
+	}
+	sort.Slice(output.Jobs, func(i, j int) bool {
+		return output.Jobs[i].Reference < output.Jobs[j].Reference
+	})
+	return output
+}
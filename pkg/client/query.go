@@ -0,0 +1,134 @@
+package client
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/********************
+ ** Status Options **
+ ********************/
+
+// statusOptions contains the options of the STATUS command used to build a job query
+type statusOptions struct {
+	states     string
+	workers    string
+	labels     stringListFlag
+	submitters string
+	since      string
+	until      string
+	arrayJobs  bool
+
+	sort       string
+	descending bool
+	page       uint
+	limit      uint
+}
+
+// register adds the status options to the flag set of the STATUS command
+func (options *statusOptions) register(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&options.states, "state", "", "states of the listed jobs")
+	flagSet.StringVar(&options.workers, "worker", "", "workers of the listed jobs")
+	flagSet.Var(&options.labels, "label", "label KEY=VALUE of the listed jobs (repeatable)")
+	flagSet.StringVar(&options.submitters, "submitter", "", "clients which submitted the listed jobs")
+	flagSet.StringVar(&options.since, "since", "", "time after which the listed jobs have been submitted")
+	flagSet.StringVar(&options.until, "until", "", "time before which the listed jobs have been submitted")
+	flagSet.BoolVar(&options.arrayJobs, "array-jobs", false, "list the jobs of the job arrays")
+
+	flagSet.StringVar(&options.sort, "sort", string(core.SortByReference), "field by which the jobs are sorted")
+	flagSet.BoolVar(&options.descending, "desc", false, "sort the jobs in descending order")
+	flagSet.UintVar(&options.page, "page", 1, "page of the listed jobs")
+	flagSet.UintVar(&options.limit, "limit", uint(core.Config.StatusPageSize), "number of jobs in a page")
+}
+
+// buildJobQuery builds the job query of the STATUS command from its options
+func (options *statusOptions) buildJobQuery(now time.Time) (core.JobQuery, error) {
+	var query core.JobQuery
+	var err error
+	for _, token := range splitCommaSeparatedList(options.states) {
+		state, err := core.ParseJobState(token)
+		if err != nil {
+			return query, err
+		}
+		query.States = append(query.States, state)
+	}
+	for _, token := range splitCommaSeparatedList(options.workers) {
+		workerId, err := strconv.ParseUint(token, 10, 32)
+		if err != nil || workerId >= uint64(core.Config.WorkerNodeCount) {
+			return query, fmt.Errorf("Invalid worker number: %s", token)
+		}
+		query.WorkerIds = append(query.WorkerIds, int(workerId))
+	}
+	if query.Labels, err = parseKeyValueList(options.labels, "label"); err != nil {
+		return query, err
+	}
+	for _, token := range splitCommaSeparatedList(options.submitters) {
+		clientId, err := strconv.ParseUint(token, 10, 32)
+		if err != nil {
+			return query, fmt.Errorf("Invalid client number: %s", token)
+		}
+		query.SubmitterIds = append(query.SubmitterIds, uint32(clientId))
+	}
+	if options.since != "" {
+		if query.SubmittedAfter, err = parsePastTime(options.since, now); err != nil {
+			return query, err
+		}
+	}
+	if options.until != "" {
+		if query.SubmittedBefore, err = parsePastTime(options.until, now); err != nil {
+			return query, err
+		}
+	}
+	query.ArrayJobs = options.arrayJobs
+
+	if query.SortBy, err = core.ParseJobSortField(options.sort); err != nil {
+		return query, err
+	}
+	query.Descending = options.descending
+	if options.page == 0 || options.limit == 0 {
+		return query, fmt.Errorf("The page and the limit must be positive")
+	}
+	// The offset of the page must fit in the query
+	if options.limit > math.MaxUint32 || options.page-1 > math.MaxUint32/options.limit {
+		return query, fmt.Errorf("The page %d of %d jobs is out of range", options.page, options.limit)
+	}
+	query.Limit = uint32(options.limit)
+	query.Offset = uint32(options.page-1) * query.Limit
+	return query, nil
+}
+
+// parsePastTime parses a time in the past: a date, a time of the day (the last occurrence) or `-<duration>`
+func parsePastTime(token string, now time.Time) (time.Time, error) {
+	if strings.HasPrefix(token, "-") {
+		delay, err := time.ParseDuration(token[1:])
+		if err != nil || delay < 0 {
+			return time.Time{}, fmt.Errorf("Invalid duration: %s", token)
+		}
+		return now.Add(-delay), nil
+	}
+
+	for _, layout := range startTimeLayoutList {
+		if pastTime, err := time.ParseInLocation(layout, token, now.Location()); err == nil {
+			return pastTime, nil
+		}
+	}
+
+	for _, layout := range startClockLayoutList {
+		clock, err := time.ParseInLocation(layout, token, now.Location())
+		if err != nil {
+			continue
+		}
+		pastTime := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location())
+		if pastTime.After(now) {
+			pastTime = pastTime.AddDate(0, 0, -1)
+		}
+		return pastTime, nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid time: %s", token)
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// Layouts accepted for the start time of a job (SUBMIT --at)
+var startTimeLayoutList = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// Layouts accepted for the start time of a job today or tomorrow (SUBMIT --at)
+var startClockLayoutList = []string{
+	"15:04:05",
+	"15:04",
+}
+
+// parseStartTime parses the start time of a job: a date, a time of the day (the next occurrence) or `+<duration>`
+func parseStartTime(token string, now time.Time) (time.Time, error) {
+	if strings.HasPrefix(token, "+") {
+		delay, err := time.ParseDuration(token[1:])
+		if err != nil || delay < 0 {
+			return time.Time{}, fmt.Errorf("Invalid delay: %s", token)
+		}
+		return now.Add(delay), nil
+	}
+
+	for _, layout := range startTimeLayoutList {
+		if startTime, err := time.ParseInLocation(layout, token, now.Location()); err == nil {
+			return startTime, nil
+		}
+	}
+
+	for _, layout := range startClockLayoutList {
+		clock, err := time.ParseInLocation(layout, token, now.Location())
+		if err != nil {
+			continue
+		}
+		startTime := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, now.Location())
+		if startTime.Before(now) {
+			startTime = startTime.AddDate(0, 0, 1)
+		}
+		return startTime, nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid time: %s", token)
+}
+
+// parseScheduleReference parses the reference `S<Id>-<Term>` of a schedule
+func parseScheduleReference(reference Ref) (core.Schedule, error) {
+	var schedule core.Schedule
+	if _, err := fmt.Sscanf(strings.ToUpper(reference), "S%d-%d", &schedule.Id, &schedule.Term); err != nil {
+		return schedule, fmt.Errorf("Invalid schedule reference: %s", reference)
+	}
+	return schedule, nil
+}
+
+// handleScheduleCommand handles the schedule command to submit a job periodically
+func (client *ClientNode) handleScheduleCommand(tokenList []string) {
+	if len(tokenList) < 3 {
+		client.printUsage(SCHEDULE_COMMAND_USAGE)
+		return
+	}
+
+	cronExpression := tokenList[1]
+	if _, err := core.ParseCronExpression(cronExpression); err != nil {
+		client.fail(err)
+		client.printUsage(SCHEDULE_COMMAND_USAGE)
+		return
+	}
+
+	flagSet := newCommandFlagSet(SCHEDULE_COMMAND)
+	options := newJobOptions()
+	options.register(flagSet)
+	if err := flagSet.Parse(tokenList[2:]); err != nil || flagSet.NArg() != 1 {
+		client.printUsage(SCHEDULE_COMMAND_USAGE)
+		return
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
+		return
+	}
+
+	jobFilePath := flagSet.Arg(0)
+	client.printProgress("Scheduling job ", jobFilePath, "... ")
+	options.stdinFile = client.resolveCommandPath(options.stdinFile)
+	job, err := loadJobFromFile(client.resolveCommandPath(jobFilePath), options, getSetOptionMap(flagSet))
+	if err != nil {
+		client.fail(err)
+		return
+	}
+
+	spec := JobSpec{Job: job, Cron: cronExpression}
+	reference, err := client.Submit(context.Background(), spec)
+	if err != nil {
+		client.printRequestError(err)
+		return
+	}
+	client.printSubmitResult(spec, reference)
+}
+
+// handleUnscheduleCommand handles the unschedule command to stop a schedule
+func (client *ClientNode) handleUnscheduleCommand(tokenList []string) {
+	if len(tokenList) != 2 {
+		client.printUsage(UNSCHEDULE_COMMAND_USAGE)
+		return
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
+		return
+	}
+
+	schedule, err := parseScheduleReference(tokenList[1])
+	if err != nil {
+		client.printUsage(UNSCHEDULE_COMMAND_USAGE)
+		return
+	}
+
+	fmt.Fprint(client.out, "Removing schedule ", schedule.GetReference(), "... ")
+	if err := client.Unschedule(context.Background(), schedule.GetReference()); err != nil {
+		client.printRequestError(err)
+		return
+	}
+	fmt.Fprintf(client.out, "Schedule %s removed.\n", schedule.GetReference())
+}
+
+// printAllSchedules prints all the schedules of the cluster
+func (client *ClientNode) printAllSchedules(scheduleMap map[string]core.Schedule) {
+	format := "%10s | %15s | %29s |\n"
+	fmt.Fprintf(client.out, format, "Schedule", "Cron", "Next run")
+	fmt.Fprintf(client.out, format, "----------", "---------------", "-----------------------------")
+	for reference, schedule := range scheduleMap {
+		cron := schedule.Cron
+		if !schedule.IsRecurring() {
+			cron = "once"
+		}
+		fmt.Fprintf(client.out, format, reference, cron, schedule.NextRun.Format(time.RFC1123))
+	}
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// Interval between two checks of the state of a followed job which is not running
+const tailPollInterval = 500 * time.Millisecond
+
+// Number of checks of a job which is not found, since a job just submitted is only in the state of
+// the leader once its entry is committed
+const tailNotFoundRetryCount = 4
+
+/**********
+ ** Tail **
+ **********/
+
+// handleTailCommand handles the tail command to follow the outputs of a job until it ends
+func (client *ClientNode) handleTailCommand(tokenList []string) {
+	if len(tokenList) != 2 {
+		client.printUsage(TAIL_COMMAND_USAGE)
+		return
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
+		return
+	}
+
+	reference := tokenList[1]
+	if _, err := core.ParseJobReference(reference); err != nil {
+		client.fail(INVALID_JOB_REFERENCE_MESSAGE)
+		return
+	}
+	// Number of the last attempt followed, which may have ended before the leader has recorded it
+	followedAttempt := uint32(0)
+	for notFoundCount := 0; ; {
+		job, _, err := client.Status(context.Background(), reference)
+		if err != nil && !errors.Is(err, ErrJobNotFound) {
+			client.printError(err)
+			return
+		}
+		if err != nil {
+			notFoundCount++
+			if notFoundCount > tailNotFoundRetryCount {
+				client.fail(INVALID_JOB_REFERENCE_MESSAGE)
+				return
+			}
+			time.Sleep(tailPollInterval)
+			continue
+		}
+		if job.IsArray() {
+			client.fail(TAIL_ARRAY_MESSAGE)
+			return
+		}
+
+		// The outputs of a job which has already ended are printed at once
+		if job.State.IsTerminal() {
+			if followedAttempt == 0 {
+				client.printJobOutputs(job)
+			}
+			fmt.Fprintf(client.out, "-- Job %s %s --\n", reference, job.State)
+			return
+		}
+
+		// The job is followed on its worker while it runs, otherwise its state is checked again later.
+		// An attempt already followed is not followed again while the leader has not recorded its end.
+		if followedAttempt <= uint32(len(job.Attempts)) {
+			if attempt := client.followJobOutput(job); attempt > 0 {
+				followedAttempt = attempt
+				continue
+			}
+		}
+		time.Sleep(tailPollInterval)
+	}
+}
+
+// followJobOutput prints the outputs of a job sent by its worker until the attempt ends. It returns
+// the number of the attempt, or 0 if the job is not running on its worker.
+func (client *ClientNode) followJobOutput(job core.Job) uint32 {
+	workerChannelList := core.Config.NodeChannelMap[core.WorkerNodeType]
+	if job.WorkerId < 0 || job.WorkerId >= len(workerChannelList) {
+		return 0
+	}
+	chunkChannel := make(chan core.OutputChunk, core.Config.ChannelBufferSize)
+	workerChannelList[job.WorkerId].SubscribeOutput <- core.OutputSubscription{
+		FromNode:     client.NodeCard,
+		JobReference: job.GetReference(),
+		Chunks:       chunkChannel,
+	}
+
+	attempt := uint32(0)
+	endsWithNewline := true
+	for chunk := range chunkChannel {
+		if attempt == 0 {
+			fmt.Fprintf(client.out, "-- Attempt %d of job %s on worker %d --\n", chunk.Attempt, job.GetReference(), job.WorkerId)
+			attempt = chunk.Attempt
+		}
+		if chunk.SkippedSize > 0 {
+			if !endsWithNewline {
+				fmt.Fprintln(client.out)
+			}
+			fmt.Fprintf(client.out, "[... %s skipped ...]\n", core.FormatMemorySize(chunk.SkippedSize))
+			endsWithNewline = true
+		}
+		if len(chunk.Data) > 0 {
+			client.printOutputChunk(chunk)
+			endsWithNewline = chunk.Data[len(chunk.Data)-1] == '\n'
+		}
+	}
+	if !endsWithNewline {
+		fmt.Fprintln(client.out)
+	}
+	return attempt
+}
+
+// printJobOutputs prints the standard output and error of a job which has ended
+func (client *ClientNode) printJobOutputs(job core.Job) {
+	client.loadJobBlobs(&job, []core.BlobField{core.StdoutBlobField, core.StderrBlobField})
+	for _, chunk := range []core.OutputChunk{{Data: []byte(job.Stdout)}, {Stderr: true, Data: []byte(job.Stderr)}} {
+		client.printOutputChunk(chunk)
+		if len(chunk.Data) > 0 && chunk.Data[len(chunk.Data)-1] != '\n' {
+			fmt.Fprintln(client.out)
+		}
+	}
+}
+
+// printOutputChunk prints a chunk of the standard output of a job on the standard output, and a
+// chunk of its standard error on the standard error
+func (client *ClientNode) printOutputChunk(chunk core.OutputChunk) {
+	if chunk.Stderr {
+		client.errOut.Write(chunk.Data)
+	} else {
+		client.out.Write(chunk.Data)
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// Token of the WAIT command to wait for all the jobs
+const WAIT_ALL_TOKEN = "ALL"
+
+/**********
+ ** Wait **
+ **********/
+
+// handleWaitCommand handles the wait command to block until a job, or all the jobs, have ended
+func (client *ClientNode) handleWaitCommand(tokenList []string) {
+	if len(tokenList) < 2 || len(tokenList) > 3 {
+		client.printUsage(WAIT_COMMAND_USAGE)
+		return
+	}
+	var timeout time.Duration
+	if len(tokenList) == 3 {
+		var err error
+		if timeout, err = time.ParseDuration(tokenList[2]); err != nil || timeout <= 0 {
+			client.printUsage(WAIT_COMMAND_USAGE)
+			return
+		}
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
+		return
+	}
+
+	// All the jobs are awaited if no reference is given
+	var referenceList []string
+	if strings.ToUpper(tokenList[1]) != WAIT_ALL_TOKEN {
+		if _, err := core.ParseJobReference(tokenList[1]); err != nil {
+			client.fail(INVALID_JOB_REFERENCE_MESSAGE)
+			return
+		}
+		referenceList = []string{tokenList[1]}
+	}
+
+	client.printProgress("Waiting... ")
+	jobMap, jobsEnded, err := client.Wait(context.Background(), referenceList, timeout)
+	if err != nil {
+		client.printError(err)
+		return
+	}
+	client.setJobsExitCode(jobMap, jobsEnded)
+	if client.outputFormat.IsStructured() {
+		client.printOutput(newWaitOutput(jobMap, jobsEnded))
+		return
+	}
+	if referenceList != nil {
+		job, ok := jobMap[referenceList[0]]
+		switch {
+		case jobsEnded:
+			fmt.Fprintln(client.out, "Done.", formatJobResult(job))
+		case ok:
+			fmt.Fprintf(client.out, "Timeout ! Job %s is still %s.\n", job.GetReference(), job.State)
+		default:
+			fmt.Fprintf(client.out, "Timeout ! Job %s has not been opened yet.\n", referenceList[0])
+		}
+	} else if jobsEnded {
+		fmt.Fprintf(client.out, "Done. All the %d jobs have ended: %s.\n", len(jobMap), formatStateCount(jobMap))
+	} else {
+		fmt.Fprintf(client.out, "Timeout ! Not all the jobs have ended: %s.\n", formatStateCount(jobMap))
+	}
+}
+
+// setJobsExitCode sets the exit code of a command waiting for jobs: the jobs have not ended before
+// the timeout, or one of them has not succeeded
+func (client *ClientNode) setJobsExitCode(jobMap map[string]core.Job, jobsEnded bool) {
+	if !jobsEnded {
+		client.exitCode = EXIT_TIMEOUT
+		return
+	}
+	for _, job := range jobMap {
+		if job.State != core.JobSucceeded {
+			client.exitCode = EXIT_JOB_FAILED
+			return
+		}
+	}
+}
+
+// formatJobResult returns the final state of a job with its exit code or signal, or the progress of a job array
+func formatJobResult(job core.Job) string {
+	result := fmt.Sprintf("Job %s %s", job.GetReference(), job.State)
+	switch {
+	case job.IsArray():
+		result += fmt.Sprintf(" (%d/%d ended)", getArrayEndedCount(job), job.ArraySize)
+	case job.Signal != "":
+		result += fmt.Sprintf(" (signal %s)", job.Signal)
+	case len(job.Attempts) > 0 && job.ExitCode != core.NO_EXIT_CODE:
+		result += fmt.Sprintf(" (exit code %d)", job.ExitCode)
+	}
+	return result + "."
+}
+
+// formatStateCount returns the number of jobs in each state, in the order of the states
+func formatStateCount(jobMap map[string]core.Job) string {
+	stateCountMap := make(map[core.JobState]int)
+	for _, job := range jobMap {
+		stateCountMap[job.State]++
+	}
+	stateList := make([]core.JobState, 0, len(stateCountMap))
+	for state := range stateCountMap {
+		stateList = append(stateList, state)
+	}
+	sort.Slice(stateList, func(i, j int) bool { return stateList[i] < stateList[j] })
+	countList := make([]string, 0, len(stateList))
+	for _, state := range stateList {
+		countList = append(countList, fmt.Sprintf("%d %s", stateCountMap[state], state))
+	}
+	if len(countList) == 0 {
+		return "no job"
+	}
+	return strings.Join(countList, ", ")
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// Duration of a watch of several jobs when no duration is given
+const watchDefaultDuration = time.Minute
+
+/***********
+ ** Watch **
+ ***********/
+
+// handleWatchCommand handles the watch command to print the state changes of jobs as the leader applies them
+func (client *ClientNode) handleWatchCommand(tokenList []string) {
+	flagSet := newCommandFlagSet(WATCH_COMMAND)
+	var labels stringListFlag
+	flagSet.Var(&labels, "label", "label KEY=VALUE which the watched jobs must have (repeatable)")
+	fromIndex := flagSet.Int64("from", -1, "index of the log entry after which the events are replayed")
+	duration := flagSet.Duration("for", 0, "duration of the watch")
+	if err := flagSet.Parse(tokenList[1:]); err != nil || flagSet.NArg() > 1 || *duration < 0 || *fromIndex < -1 || *fromIndex > int64(^uint32(0)) {
+		client.printUsage(WATCH_COMMAND_USAGE)
+		return
+	}
+	labelMap, err := parseKeyValueList(labels, "label")
+	if err != nil {
+		client.fail(err)
+		client.printUsage(WATCH_COMMAND_USAGE)
+		return
+	}
+
+	if !isClusterStarted() {
+		client.fail(NOT_STARTED_MESSAGE)
+		return
+	}
+
+	options := WatchOptions{
+		JobReference: flagSet.Arg(0),
+		Labels:       labelMap,
+		Replay:       *fromIndex >= 0,
+	}
+	if options.Replay {
+		options.FromIndex = uint32(*fromIndex)
+	}
+	if options.JobReference != "" {
+		if _, err := core.ParseJobReference(options.JobReference); err != nil {
+			client.fail(INVALID_JOB_REFERENCE_MESSAGE)
+			return
+		}
+	} else if *duration == 0 {
+		*duration = watchDefaultDuration
+	}
+
+	if *duration > 0 {
+		fmt.Fprintf(client.out, "Watching for %s...\n", *duration)
+	} else {
+		fmt.Fprintf(client.out, "Watching job %s until it ends...\n", options.JobReference)
+	}
+	ctx := context.Background()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+	var jobEndedError *JobEndedError
+	err = client.Watch(ctx, options, client.printJobEvent)
+	switch {
+	case errors.As(err, &jobEndedError):
+		fmt.Fprintln(client.out, "The job has already ended.", formatJobResult(jobEndedError.Job))
+	case err != nil && !errors.Is(err, context.DeadlineExceeded):
+		client.printError(err)
+		return
+	}
+	fmt.Fprintln(client.out, "Done.")
+}
+
+// printJobEvent prints a state change of a job
+func (client *ClientNode) printJobEvent(event core.JobEvent) {
+	fmt.Fprintf(client.out, "[%d] %s : %s", event.Index, event.EntryType, formatJobResult(event.Job))
+	if event.EntryType == core.CloseJob && event.Job.State == core.JobWaiting && !event.Job.IsArray() {
+		fmt.Fprintf(client.out, " Attempt %d failed, retry at %s.", len(event.Job.Attempts), event.Job.RetryAt.Format("15:04:05"))
+	}
+	fmt.Fprintln(client.out)
+}
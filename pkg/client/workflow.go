@@ -0,0 +1,130 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/**************
+ ** Workflow **
+ **************/
+
+// WorkflowJob is a job of a workflow. It is identified by its name inside the workflow.
+type WorkflowJob struct {
+	Name string `json:"name"`
+	// Path of the job file or job manifest, relative to the workflow file
+	File     string `json:"file"`
+	Priority string `json:"priority"`
+	// Names of the jobs which must succeed before this job
+	After []string `json:"after"`
+}
+
+// Workflow is a DAG of jobs loaded from a JSON file of the following form:
+//
+//	{
+//	  "jobs": [
+//	    {"name": "hello", "file": "job-basic-hello.cpp"},
+//	    {"name": "prime", "file": "job-medium-prime.cpp", "priority": "high", "after": ["hello"]}
+//	  ]
+//	}
+type Workflow struct {
+	Jobs []WorkflowJob `json:"jobs"`
+}
+
+// LoadWorkflowFromFile loads a workflow and sorts its jobs so that each job comes after its parents
+func LoadWorkflowFromFile(path string) (*Workflow, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var workflow Workflow
+	if err := json.Unmarshal(content, &workflow); err != nil {
+		return nil, err
+	}
+	if len(workflow.Jobs) == 0 {
+		return nil, fmt.Errorf("The workflow does not contain any job")
+	}
+
+	for i := range workflow.Jobs {
+		if !filepath.IsAbs(workflow.Jobs[i].File) {
+			workflow.Jobs[i].File = filepath.Join(filepath.Dir(path), workflow.Jobs[i].File)
+		}
+	}
+
+	if err := workflow.sortJobs(); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// sortJobs sorts the jobs in topological order and checks that the workflow is a valid DAG
+func (workflow *Workflow) sortJobs() error {
+	jobMap := make(map[string]WorkflowJob)
+	for _, job := range workflow.Jobs {
+		if job.Name == "" {
+			return fmt.Errorf("Each job of the workflow must have a name")
+		}
+		if _, ok := jobMap[job.Name]; ok {
+			return fmt.Errorf("The job name %s is used several times", job.Name)
+		}
+		jobMap[job.Name] = job
+	}
+
+	sortedJobs := make([]WorkflowJob, 0, len(workflow.Jobs))
+	visited := make(map[string]bool)
+	inProgress := make(map[string]bool)
+	var visit func(job WorkflowJob) error
+	visit = func(job WorkflowJob) error {
+		if visited[job.Name] {
+			return nil
+		}
+		if inProgress[job.Name] {
+			return fmt.Errorf("The workflow contains a cycle through the job %s", job.Name)
+		}
+		inProgress[job.Name] = true
+		for _, parentName := range job.After {
+			parent, ok := jobMap[parentName]
+			if !ok {
+				return fmt.Errorf("The job %s depends on the unknown job %s", job.Name, parentName)
+			}
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		inProgress[job.Name] = false
+		visited[job.Name] = true
+		sortedJobs = append(sortedJobs, job)
+		return nil
+	}
+
+	for _, job := range workflow.Jobs {
+		if err := visit(job); err != nil {
+			return err
+		}
+	}
+	workflow.Jobs = sortedJobs
+	return nil
+}
+
+// ToJob builds the job to submit. referenceMap gives the reference of the jobs already submitted.
+func (workflowJob *WorkflowJob) ToJob(referenceMap map[string]string) (core.Job, error) {
+	options := newJobOptions()
+	setOptionMap := make(map[string]bool)
+	if workflowJob.Priority != "" {
+		options.priority = workflowJob.Priority
+		setOptionMap["priority"] = true
+	}
+	job, err := loadJobFromFile(workflowJob.File, options, setOptionMap)
+	if err != nil {
+		return core.Job{}, err
+	}
+
+	for _, parentName := range workflowJob.After {
+		job.Dependencies = append(job.Dependencies, referenceMap[parentName])
+	}
+	return job, nil
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Extensions of the archive files which can be submitted as a job
+var ArchiveExtensionList = []string{".tar", ".tar.gz", ".tgz"}
+
+/*************
+ ** Archive **
+ *************/
+
+// IsArchiveFile checks if a file is a tar archive from its extension
+func IsArchiveFile(path string) bool {
+	for _, extension := range ArchiveExtensionList {
+		if strings.HasSuffix(strings.ToLower(path), extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateArchive creates a gzipped tar archive of files and directories. Their names in the archive
+// are relative to the root directory. The symbolic links are not followed, and a file reached
+// through a symbolic link to a directory is rejected if it is not in the root directory.
+func CreateArchive(root string, pathList []string) ([]byte, error) {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	addFile := func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil || !isInDirectory(root, path) {
+			return fmt.Errorf("The file %s is not in the directory %s", path, root)
+		}
+		// A directory of the path may be a symbolic link to another directory
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil || !isInDirectory(realRoot, realPath) {
+			return fmt.Errorf("The file %s is not in the directory %s", path, root)
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// The modification time is not kept so that the same files always give the same archive
+		header := &tar.Header{
+			Name: filepath.ToSlash(name),
+			Mode: int64(info.Mode().Perm()),
+			Size: int64(len(content)),
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err = tarWriter.Write(content)
+		return err
+	}
+
+	for _, path := range pathList {
+		if err := filepath.Walk(path, addFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// isInDirectory checks if a path is in a directory, without resolving the symbolic links
+func isInDirectory(directory string, path string) bool {
+	name, err := filepath.Rel(directory, path)
+	return err == nil && name != ".." && !strings.HasPrefix(name, ".."+string(filepath.Separator))
+}
+
+// openArchive returns a reader of a tar archive, which may be gzipped
+func openArchive(archive []byte) (*tar.Reader, error) {
+	reader := bufio.NewReader(bytes.NewReader(archive))
+	magic, _ := reader.Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		return tar.NewReader(gzipReader), nil
+	}
+	return tar.NewReader(reader), nil
+}
+
+// ListArchive returns the names of the files of a tar archive
+func ListArchive(archive []byte) ([]string, error) {
+	tarReader, err := openArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	var nameList []string
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nameList, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag == tar.TypeReg {
+			nameList = append(nameList, path.Clean(header.Name))
+		}
+	}
+}
+
+// ExtractArchive extracts the files and directories of a tar archive into a directory. The other
+// entries (links, devices, ...) are ignored and the files can not be extracted outside the directory.
+func ExtractArchive(archive []byte, directory string) error {
+	tarReader, err := openArchive(archive)
+	if err != nil {
+		return err
+	}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("Invalid file name in archive: %s", header.Name)
+		}
+		path := filepath.Join(directory, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm()|0600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, tarReader)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+/***************
+ ** Artifacts **
+ ***************/
+
+// CheckArtifactPattern checks that a pattern of artifacts is valid and relative to the workspace of the job
+func CheckArtifactPattern(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("Invalid artifact pattern: %s", pattern)
+	}
+	name := filepath.Clean(pattern)
+	if pattern == "" || filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("Invalid artifact pattern: %s. It must be relative to the workspace of the job", pattern)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package core
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeArchiveTestFile writes a file and its parent directories
+func writeArchiveTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestArchive creates an uncompressed tar archive containing a file for each of the given names
+func newTestArchive(t *testing.T, nameList ...string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	tarWriter := tar.NewWriter(&buffer)
+	for _, name := range nameList {
+		header := &tar.Header{Name: name, Mode: 0644, Size: 2, Typeflag: tar.TypeReg}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte("ok")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// An archive must not extract files outside of its directory
+func TestExtractArchivePathTraversal(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"main.cpp", true},
+		{"src/main.cpp", true},
+		{"./src/../main.cpp", true},
+		{"..data", true},
+		{"../main.cpp", false},
+		{"src/../../main.cpp", false},
+		{"/tmp/main.cpp", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parent := t.TempDir()
+			directory := filepath.Join(parent, "workspace")
+			err := ExtractArchive(newTestArchive(t, test.name), directory)
+			if test.valid && err != nil {
+				t.Fatal(err)
+			}
+			if !test.valid && err == nil {
+				t.Fatalf("the file %s has been extracted", test.name)
+			}
+			if _, err := os.Stat(filepath.Join(parent, "main.cpp")); err == nil {
+				t.Fatalf("the file %s has been extracted outside of the directory", test.name)
+			}
+		})
+	}
+}
+
+// The files of an archive created by CreateArchive must be extracted with the same content
+func TestCreateArchive(t *testing.T) {
+	root := t.TempDir()
+	writeArchiveTestFile(t, filepath.Join(root, "main.cpp"), "int main() {}")
+	writeArchiveTestFile(t, filepath.Join(root, "src", "lib.cpp"), "int f() { return 1; }")
+
+	archive, err := CreateArchive(root, []string{filepath.Join(root, "main.cpp"), filepath.Join(root, "src")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nameList, err := ListArchive(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nameList) != 2 || nameList[0] != "main.cpp" || nameList[1] != "src/lib.cpp" {
+		t.Fatalf("the archive contains %v", nameList)
+	}
+
+	directory := t.TempDir()
+	if err := ExtractArchive(archive, directory); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(directory, "src", "lib.cpp"))
+	if err != nil || string(content) != "int f() { return 1; }" {
+		t.Fatalf("the file src/lib.cpp has been extracted with %q, %v", content, err)
+	}
+}
+
+// An archive must not contain the files of a directory outside of its root, even through a
+// symbolic link
+func TestCreateArchiveOutsideRoot(t *testing.T) {
+	root := t.TempDir()
+	secretDirectory := t.TempDir()
+	writeArchiveTestFile(t, filepath.Join(secretDirectory, "key"), "secret")
+
+	if _, err := CreateArchive(root, []string{secretDirectory}); err == nil {
+		t.Fatal("the files outside of the root have been archived")
+	}
+	if err := os.Symlink(secretDirectory, filepath.Join(root, "link")); err != nil {
+		t.Skip("symbolic links are not supported: ", err)
+	}
+	if _, err := CreateArchive(root, []string{filepath.Join(root, "link", "key")}); err == nil {
+		t.Fatal("the files of a symlinked directory have been archived")
+	}
+}
@@ -0,0 +1,287 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+/****************
+ ** Blob Field **
+ ****************/
+
+// BlobField is a field of a job whose content can be stored in a blob store instead of the log
+type BlobField string
+
+const (
+	InputBlobField      BlobField = "input"
+	ArchiveBlobField    BlobField = "archive"
+	StdinBlobField      BlobField = "stdin"
+	CompileLogBlobField BlobField = "compile log"
+	StdoutBlobField     BlobField = "stdout"
+	StderrBlobField     BlobField = "stderr"
+	ArtifactsBlobField  BlobField = "artifacts"
+)
+
+// Fields given by the user when the job is submitted
+var JobInputBlobFieldList = []BlobField{InputBlobField, ArchiveBlobField, StdinBlobField}
+
+// Fields set by the worker when the job is executed
+var JobResultBlobFieldList = []BlobField{CompileLogBlobField, StdoutBlobField, StderrBlobField, ArtifactsBlobField}
+
+/********************
+ ** Blob Reference **
+ ********************/
+
+// BlobReference references a content stored in the blob store of a node
+type BlobReference struct {
+	Hash string
+	Size uint64
+	// Node from which the content can be fetched
+	Node NodeCard
+}
+
+// Convert a BlobReference to a string representation
+func (reference BlobReference) String() string {
+	return fmt.Sprintf("blob %.12s (%s) on %s", reference.Hash, FormatMemorySize(reference.Size), reference.Node)
+}
+
+// HashBlob returns the hash identifying a content in the blob stores
+func HashBlob(content []byte) string {
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+/****************
+ ** Blob Store **
+ ****************/
+
+// BlobStore stores contents identified by their hash in a local directory of a node. The contents
+// stored by other nodes are fetched on demand and kept in the local directory.
+type BlobStore struct {
+	directory string
+	card      NodeCard
+}
+
+// NewBlobStore creates the blob store of a node. It returns nil if the blob stores are disabled or
+// if the store can not be created.
+func NewBlobStore(card NodeCard) *BlobStore {
+	if Config.BlobInlineThreshold == 0 {
+		return nil
+	}
+	directory := filepath.Join(Config.BlobDirectory, fmt.Sprintf("%s-%d", strings.ToLower(card.Type.String()), card.Id))
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		Logger.Error("Error while creating blob store", zap.String("Directory", directory), zap.Error(err))
+		return nil
+	}
+	return &BlobStore{directory: directory, card: card}
+}
+
+// Put stores a content and returns its reference
+func (store *BlobStore) Put(content []byte) (BlobReference, error) {
+	reference := BlobReference{Hash: HashBlob(content), Size: uint64(len(content)), Node: store.card}
+	path := filepath.Join(store.directory, reference.Hash)
+	if _, err := os.Stat(path); err == nil {
+		return reference, nil
+	}
+	// The content is written to a temporary file first so that an incomplete blob is never read
+	temporaryFile, err := ioutil.TempFile(store.directory, reference.Hash+".*.tmp")
+	if err != nil {
+		return reference, err
+	}
+	_, err = temporaryFile.Write(content)
+	if closeErr := temporaryFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temporaryFile.Name(), path)
+	}
+	if err != nil {
+		os.Remove(temporaryFile.Name())
+	}
+	return reference, err
+}
+
+// Get returns the content of a hash stored in the local directory
+func (store *BlobStore) Get(hash string) ([]byte, error) {
+	if len(hash) != sha256.Size*2 || strings.Trim(hash, "0123456789abcdef") != "" {
+		return nil, fmt.Errorf("Invalid blob hash: %s", hash)
+	}
+	content, err := ioutil.ReadFile(filepath.Join(store.directory, hash))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("Blob %s not found on %s", hash, store.card)
+	}
+	return content, err
+}
+
+// Fetch returns the content of a reference, from the local directory if it is there or from the
+// node storing it otherwise
+func (store *BlobStore) Fetch(reference BlobReference) ([]byte, error) {
+	if content, err := store.Get(reference.Hash); err == nil {
+		return content, nil
+	}
+	content, err := requestBlob(store.card, reference)
+	if err != nil {
+		return nil, err
+	}
+	if HashBlob(content) != reference.Hash {
+		return nil, fmt.Errorf("Blob %s fetched from %s is corrupted", reference.Hash, reference.Node)
+	}
+	if _, err := store.Put(content); err != nil {
+		Logger.Warn("Error while storing fetched blob",
+			zap.String("Node", store.card.String()),
+			zap.String("Hash", reference.Hash),
+			zap.Error(err),
+		)
+	}
+	return content, nil
+}
+
+// Serve answers the requests of the other nodes for the contents of the store
+func (store *BlobStore) Serve(requestChannel chan BlobRequest) {
+	for request := range requestChannel {
+		content, err := store.Get(request.Hash)
+		response := BlobResponse{Content: content}
+		if err != nil {
+			response.Error = err.Error()
+		}
+		request.Response <- response
+	}
+}
+
+// requestBlob requests the content of a reference from the node storing it
+func requestBlob(fromNode NodeCard, reference BlobReference) ([]byte, error) {
+	nodeChannelList := Config.NodeChannelMap[reference.Node.Type]
+	if int(reference.Node.Id) >= len(nodeChannelList) || nodeChannelList[reference.Node.Id].RequestBlob == nil {
+		return nil, fmt.Errorf("Blob %s can not be fetched from %s", reference.Hash, reference.Node)
+	}
+	responseChannel := make(chan BlobResponse, 1)
+	request := BlobRequest{FromNode: fromNode, Hash: reference.Hash, Response: responseChannel}
+	select {
+	case nodeChannelList[reference.Node.Id].RequestBlob <- request:
+	case <-time.After(Config.BlobFetchTimeout):
+		return nil, fmt.Errorf("%s is not responding to the request for blob %s", reference.Node, reference.Hash)
+	}
+	select {
+	case response := <-responseChannel:
+		if response.Error != "" {
+			return nil, errors.New(response.Error)
+		}
+		return response.Content, nil
+	case <-time.After(Config.BlobFetchTimeout):
+		return nil, fmt.Errorf("%s is not responding to the request for blob %s", reference.Node, reference.Hash)
+	}
+}
+
+/***************
+ ** Job Blobs **
+ ***************/
+
+// getBlobFieldContent returns the content of a blob field of the job
+func (job *Job) getBlobFieldContent(field BlobField) []byte {
+	switch field {
+	case InputBlobField:
+		return []byte(job.Input)
+	case ArchiveBlobField:
+		return job.Archive
+	case StdinBlobField:
+		return []byte(job.Stdin)
+	case CompileLogBlobField:
+		return []byte(job.CompileLog)
+	case StdoutBlobField:
+		return []byte(job.Stdout)
+	case StderrBlobField:
+		return []byte(job.Stderr)
+	case ArtifactsBlobField:
+		return job.Artifacts
+	}
+	return nil
+}
+
+// setBlobFieldContent sets the content of a blob field of the job
+func (job *Job) setBlobFieldContent(field BlobField, content []byte) {
+	switch field {
+	case InputBlobField:
+		job.Input = string(content)
+	case ArchiveBlobField:
+		job.Archive = content
+	case StdinBlobField:
+		job.Stdin = string(content)
+	case CompileLogBlobField:
+		job.CompileLog = string(content)
+	case StdoutBlobField:
+		job.Stdout = string(content)
+	case StderrBlobField:
+		job.Stderr = string(content)
+	case ArtifactsBlobField:
+		job.Artifacts = content
+	}
+}
+
+// copyBlobs returns a copy of the blob references of the job, since the copies of a job share them
+func (job *Job) copyBlobs() map[BlobField]BlobReference {
+	blobMap := make(map[BlobField]BlobReference, len(job.Blobs))
+	for field, reference := range job.Blobs {
+		blobMap[field] = reference
+	}
+	return blobMap
+}
+
+// StoreBlobs moves the content of the given fields larger than Config.BlobInlineThreshold to a blob
+// store, so that only their reference is in the log. Nothing is moved if the store is nil.
+func (job *Job) StoreBlobs(store *BlobStore, fieldList []BlobField) error {
+	if store == nil {
+		return nil
+	}
+	job.Blobs = job.copyBlobs()
+	for _, field := range fieldList {
+		content := job.getBlobFieldContent(field)
+		if uint64(len(content)) <= uint64(Config.BlobInlineThreshold) {
+			continue
+		}
+		reference, err := store.Put(content)
+		if err != nil {
+			return fmt.Errorf("Error while storing the %s of the job: %v", field, err)
+		}
+		job.Blobs[field] = reference
+		job.setBlobFieldContent(field, nil)
+	}
+	return nil
+}
+
+// LoadBlobs fetches the content of the given fields stored in blob stores back into the job
+func (job *Job) LoadBlobs(store *BlobStore, fieldList []BlobField) error {
+	job.Blobs = job.copyBlobs()
+	for _, field := range fieldList {
+		reference, ok := job.Blobs[field]
+		if !ok {
+			continue
+		}
+		if store == nil {
+			return fmt.Errorf("The %s of the job is in %s but the blob store is disabled", field, reference)
+		}
+		content, err := store.Fetch(reference)
+		if err != nil {
+			return fmt.Errorf("Error while fetching the %s of the job: %v", field, err)
+		}
+		job.setBlobFieldContent(field, content)
+		delete(job.Blobs, field)
+	}
+	return nil
+}
+
+// ClearBlobs removes the references of the given fields, whose content is replaced
+func (job *Job) ClearBlobs(fieldList []BlobField) {
+	job.Blobs = job.copyBlobs()
+	for _, field := range fieldList {
+		delete(job.Blobs, field)
+	}
+}
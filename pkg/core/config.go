@@ -21,6 +21,8 @@ var Config = struct {
 	MinElectionTimeout   time.Duration
 	MaxElectionTimeout   time.Duration
 	MaxFindLeaderTimeout time.Duration
+	// Maximum time for which the leader holds a WaitCommand before answering that the jobs have not ended yet
+	WaitPollTimeout time.Duration
 
 	// INTERVALS
 	// Repeat interval for leader after it has sent out heartbeat
@@ -28,6 +30,78 @@ var Config = struct {
 
 	// RETRY
 	MaxRetryToFindLeader uint32
+
+	// WATCH
+	// Maximum number of job events queued for a subscriber which does not read them. The subscription
+	// is ended beyond, and the subscriber resumes it from the last event it has received.
+	MaxWatchQueueSize uint32
+	// Number of job events kept by each scheduler node to replay them to the subscribers. The events
+	// of older entries can not be replayed anymore.
+	MaxWatchHistorySize uint32
+
+	// JOBS
+	// Let a job with a higher priority preempt the running job of a worker if it has a lower priority:
+	// the running job is stopped and put back in the queue of the worker, to be executed again. A job
+	// is not preempted anymore once it has been preempted MaxJobPreemptions times.
+	JobPreemption     bool
+	MaxJobPreemptions uint32
+	// Maximum number of jobs of a job array
+	MaxArraySize uint32
+	// Maximum size in bytes of the archive of a multi-file job
+	MaxArchiveSize uint32
+	// Maximum size in bytes of the archive of the artifacts collected after the execution of a job
+	MaxArtifactSize uint32
+	// Number of jobs listed by STATUS when no limit is given
+	StatusPageSize uint32
+
+	// COMPILATION
+	// Compilers which can be used by the jobs on the workers
+	CompilerList []string
+	// Patterns (see path.Match, where `*` also matches `/`) of the compiler flags which can be used by
+	// the jobs on the workers
+	CompilerFlagPatternList []string
+	// Directory of the compile cache, with a subdirectory for each worker, and maximum size in bytes
+	// of the cache of each worker (the cache is disabled if 0)
+	CompileCacheDirectory string
+	CompileCacheSize      uint64
+
+	// WORKSPACES
+	// Directory of the workspaces in which the jobs are built and executed, with a subdirectory for
+	// each worker (the temporary directory of the system if empty)
+	WorkspaceDirectory string
+	// Keep the workspace of each job after its execution for debugging
+	KeepWorkspaces bool
+
+	// SANDBOX
+	// Ids of the workers which build and execute the jobs in a sandbox (Linux only): new user, mount,
+	// PID and network namespaces, read-only root, private /tmp and seccomp filter
+	SandboxWorkerIdList []uint32
+
+	// CGROUPS
+	// cgroup v2 directory in which the workers create a cgroup for each job, to enforce its limits and
+	// measure its resource usage (disabled if empty or if cgroup v2 is not available)
+	CgroupDirectory string
+
+	// BLOBS
+	// Directory of the blob stores, with a subdirectory for each client and worker, and maximum size in
+	// bytes of a job input or output embedded in the log. Larger contents are stored in the blob store
+	// of the node which created them and are fetched on demand (the blob stores are disabled if 0).
+	BlobDirectory       string
+	BlobInlineThreshold uint32
+	// Maximum time to wait for a node to send a requested blob
+	BlobFetchTimeout time.Duration
+
+	// COMMAND LINE
+	// Unix socket on which the cluster serves the one-shot commands of the command line (for example
+	// `job_scheduler submit job.cpp`), and number of clients running these commands concurrently
+	ControlSocketPath      string
+	CommandLineClientCount uint32
+
+	// HTTP GATEWAY
+	// TCP address on which the cluster serves the HTTP API of the job queue (disabled if empty), for
+	// example localhost:8080. The API has no authentication: anyone who can reach it can run code on
+	// the workers.
+	GatewayAddress string
 }{
 	SchedulerNodeCount: 5,
 	WorkerNodeCount:    2,
@@ -37,8 +111,44 @@ var Config = struct {
 	MinElectionTimeout:   150 * time.Millisecond,
 	MaxElectionTimeout:   300 * time.Millisecond,
 	MaxFindLeaderTimeout: 300 * time.Millisecond,
+	WaitPollTimeout:      5 * time.Second,
 
 	IsAliveNotificationInterval: 50 * time.Millisecond,
 
 	MaxRetryToFindLeader: 3,
+
+	MaxWatchQueueSize:   10000,
+	MaxWatchHistorySize: 100000,
+
+	JobPreemption:     true,
+	MaxJobPreemptions: 3,
+	MaxArraySize:      1000,
+	MaxArchiveSize:    16 << 20,
+	MaxArtifactSize:   64 << 20,
+	StatusPageSize:    50,
+
+	CompilerList: []string{"g++", "clang++", "gcc", "clang"},
+	CompilerFlagPatternList: []string{
+		"-O", "-O[0-3sgz]", "-Ofast", "-g", "-std=*", "-D*", "-U*", "-l*", "-pthread",
+		"-Wall", "-Wextra", "-Werror", "-Wpedantic", "-pedantic", "-w",
+		"-march=*", "-mtune=*", "-fopenmp", "-ffast-math", "-funroll-loops", "-fno-exceptions", "-fno-rtti",
+	},
+	CompileCacheDirectory: "cache",
+	CompileCacheSize:      256 << 20,
+
+	WorkspaceDirectory: "",
+	KeepWorkspaces:     false,
+
+	SandboxWorkerIdList: []uint32{},
+
+	CgroupDirectory: "/sys/fs/cgroup/algorep",
+
+	BlobDirectory:       "blobs",
+	BlobInlineThreshold: 64 << 10,
+	BlobFetchTimeout:    5 * time.Second,
+
+	ControlSocketPath:      "job_scheduler.sock",
+	CommandLineClientCount: 4,
+
+	GatewayAddress: "",
 }
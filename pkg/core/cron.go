@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of years searched to find the next run of a cron expression
+const maxCronSearchYears = 5
+
+// Shortcuts which can be used instead of the 5 fields of a cron expression
+var cronShortcutMap = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+/*********************
+ ** Cron Expression **
+ *********************/
+
+// CronExpression is a parsed cron expression with the 5 usual fields: minute, hour, day of month,
+// month and day of week. Each field accepts `*`, numbers, ranges `a-b`, steps `*/n` or `a-b/n` and
+// lists of them separated by commas.
+type CronExpression struct {
+	minute     map[int]bool
+	hour       map[int]bool
+	dayOfMonth map[int]bool
+	month      map[int]bool
+	dayOfWeek  map[int]bool
+	// A day matches both day fields if one of them starts with `*` (for example `*/2`), and any of them
+	// otherwise
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// ParseCronExpression parses a cron expression such as `*/5 * * * *` or `@daily`
+func ParseCronExpression(expression string) (*CronExpression, error) {
+	if shortcut, ok := cronShortcutMap[strings.ToLower(strings.TrimSpace(expression))]; ok {
+		expression = shortcut
+	}
+	fieldList := strings.Fields(expression)
+	if len(fieldList) != 5 {
+		return nil, fmt.Errorf("Invalid cron expression %q: 5 fields expected", expression)
+	}
+
+	cron := &CronExpression{}
+	var err error
+	if cron.minute, err = parseCronField(fieldList[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if cron.hour, err = parseCronField(fieldList[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if cron.dayOfMonth, err = parseCronField(fieldList[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if cron.month, err = parseCronField(fieldList[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if cron.dayOfWeek, err = parseCronField(fieldList[4], 0, 7); err != nil {
+		return nil, err
+	}
+	// Sunday is both 0 and 7
+	if cron.dayOfWeek[7] {
+		cron.dayOfWeek[0] = true
+	}
+	cron.anyDayOfMonth = strings.HasPrefix(fieldList[2], "*")
+	cron.anyDayOfWeek = strings.HasPrefix(fieldList[4], "*")
+	return cron, nil
+}
+
+// parseCronField parses one field of a cron expression and returns the set of allowed values
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	valueMap := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return nil, fmt.Errorf("Invalid step in cron field %q", field)
+			}
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			startPart, endPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(startPart); err != nil {
+				return nil, fmt.Errorf("Invalid value in cron field %q", field)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endPart); err != nil {
+					return nil, fmt.Errorf("Invalid range in cron field %q", field)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("Cron field %q must be between %d and %d", field, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			valueMap[value] = true
+		}
+	}
+	return valueMap, nil
+}
+
+// matchDay checks if the day of a time matches the day of month and day of week fields
+func (cron *CronExpression) matchDay(t time.Time) bool {
+	dayOfMonth := cron.dayOfMonth[t.Day()]
+	dayOfWeek := cron.dayOfWeek[int(t.Weekday())]
+	if cron.anyDayOfMonth || cron.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// Next returns the first time strictly after t matching the expression, or the zero time if there is none
+func (cron *CronExpression) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxCronSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !cron.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !cron.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !cron.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !cron.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// Invalid cron expressions must be rejected
+func TestParseCronExpressionErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@never",
+	} {
+		if _, err := ParseCronExpression(expression); err == nil {
+			t.Fatalf("the cron expression %q is accepted", expression)
+		}
+	}
+}
+
+// Next must return the first matching minute strictly after the given time
+func TestCronExpressionNext(t *testing.T) {
+	// Wednesday
+	start := time.Date(2024, time.January, 10, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"* * * * *", time.Date(2024, time.January, 10, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, time.January, 10, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2024, time.January, 11, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, time.January, 10, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, time.January, 10, 11, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"@YEARLY", time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// Sunday is both 0 and 7
+		{"0 0 * * 7", time.Date(2024, time.January, 14, 0, 0, 0, 0, time.UTC)},
+		// A day matches if it matches any of the two restricted day fields
+		{"0 0 20 * 5", time.Date(2024, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		// A day field starting with `*` is not a restriction on its own: both fields must match
+		{"0 0 */2 * 1", time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * */2", time.Date(2024, time.January, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			cron, err := ParseCronExpression(test.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next := cron.Next(start); !next.Equal(test.expected) {
+				t.Fatalf("Next(%v) = %v instead of %v", start, next, test.expected)
+			}
+		})
+	}
+}
@@ -15,11 +15,14 @@ type EntryType int
 const (
 	OpenJob = iota
 	CloseJob
+	AddSchedule
+	RemoveSchedule
+	CancelJob
 )
 
 // Convert an EntryType to a string
 func (e EntryType) String() string {
-	return [...]string{"OpenJob", "CloseJob"}[e]
+	return [...]string{"OpenJob", "CloseJob", "AddSchedule", "RemoveSchedule", "CancelJob"}[e]
 }
 
 /***********
@@ -31,6 +34,8 @@ type Entry struct {
 	Type EntryType
 	Term uint32
 	Job  Job
+	// Used for AddSchedule and RemoveSchedule
+	Schedule Schedule
 }
 
 /***************************
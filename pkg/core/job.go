@@ -3,6 +3,10 @@ package core
 import (
 	"fmt"
 	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"time"
 )
 
 const NO_WORKER = -1
@@ -15,12 +19,79 @@ type JobState int
 
 const (
 	JobWaiting = iota
-	JobDone
+	JobSucceeded
+	JobFailed
+	JobSkipped
+	JobCancelled
 )
 
 // Convert a JobStatus to a string
 func (s JobState) String() string {
-	return [...]string{"WAITING", "DONE"}[s]
+	return [...]string{"WAITING", "SUCCEEDED", "FAILED", "SKIPPED", "CANCELLED"}[s]
+}
+
+// IsTerminal returns true if the job will not change state anymore
+func (s JobState) IsTerminal() bool {
+	return s != JobWaiting
+}
+
+/******************
+ ** Job Priority **
+ ******************/
+
+type JobPriority int
+
+const (
+	LowPriority JobPriority = iota
+	MediumPriority
+	HighPriority
+)
+
+// Convert a JobPriority to a string
+func (p JobPriority) String() string {
+	return [...]string{"LOW", "MEDIUM", "HIGH"}[p]
+}
+
+// ParseJobPriority converts a string (low, medium or high) to a JobPriority
+func ParseJobPriority(token string) (JobPriority, error) {
+	for _, priority := range []JobPriority{LowPriority, MediumPriority, HighPriority} {
+		if strings.ToUpper(token) == priority.String() {
+			return priority, nil
+		}
+	}
+	return MediumPriority, fmt.Errorf("Invalid priority: %s", token)
+}
+
+/******************
+ ** Job Language **
+ ******************/
+
+// Language of a job when it is not given
+const DEFAULT_LANGUAGE = "c++"
+
+// Compiler of a job when it is not given
+const DEFAULT_COMPILER = "g++"
+
+// Executable built from the archive of a multi-file job when it is not given
+const DEFAULT_EXECUTABLE = "job.out"
+
+// Languages which can be compiled by the workers
+var LanguageList = []string{"c++", "c"}
+
+// Extensions of the source files compiled for each language when a multi-file job has no build command
+var SourceExtensionMap = map[string][]string{
+	"c++": {".cpp", ".cc", ".cxx", ".c++"},
+	"c":   {".c"},
+}
+
+// IsValidLanguage checks if a language can be compiled by the workers
+func IsValidLanguage(language string) bool {
+	for _, validLanguage := range LanguageList {
+		if language == validLanguage {
+			return true
+		}
+	}
+	return false
 }
 
 /*********
@@ -32,15 +103,225 @@ type Job struct {
 	Term     uint32
 	State    JobState
 	WorkerId int
-	Input    string
-	Output   string
+	Priority JobPriority
+	// References of the jobs which must succeed before this job is executed
+	Dependencies []string
+	Input        string
+	// Labels given by the user to describe the job
+	Labels map[string]string
+
+	// Language of the input, compiler and flags given to the compiler
+	Language      string
+	Compiler      string
+	CompilerFlags []string
+	// Files of a multi-file job as a tar archive, possibly gzipped, used instead of the input. They
+	// are built by the build command, or by make if there is a Makefile, or by compiling all the
+	// source files otherwise, and the build creates the executable.
+	Archive      []byte
+	BuildCommand string
+	Executable   string
+
+	// Arguments, standard input and environment variables given to the job when it is executed
+	Args  []string
+	Stdin string
+	Env   map[string]string
+	// Patterns (see filepath.Match) of the files of the workspace collected as artifacts after the execution
+	ArtifactPatterns []string
+
+	// Maximum duration of the execution of the job (no limit if 0)
+	Timeout time.Duration
+	// Maximum CPU time and memory in bytes used by the execution of the job (no limit if 0)
+	CPULimit    time.Duration
+	MemoryLimit uint64
+	// Number of CPUs used by the execution of the job, enforced by its cgroup (no limit if 0)
+	CPUQuota    float64
+	RetryPolicy RetryPolicy
+	Attempts    []JobAttempt
+
+	// Result of the last attempt: exit code (NO_EXIT_CODE if the job did not exit), name of the
+	// signal which terminated the job, output of the build and outputs of the execution
+	ExitCode   int
+	Signal     string
+	CompileLog string
+	Stdout     string
+	Stderr     string
+	// Files collected after the execution as a gzipped tar archive, and their names
+	Artifacts    []byte
+	ArtifactList []string
+	// References of the contents larger than Config.BlobInlineThreshold, stored in the blob store of
+	// a node instead of the log. The field of a stored content is empty.
+	Blobs map[BlobField]BlobReference
+	// Time after which the job can be executed again after a failed attempt
+	RetryAt time.Time
+	// Number of times the execution of the job has been preempted by a job with a higher priority
+	PreemptionCount uint32
+
+	SubmittedAt time.Time
+	// Node which submitted the job, or the schedule which created it
+	Submitter NodeCard
+	// Reference of the schedule which created the job and time at which it was planned
+	ScheduleReference string
+	ScheduledAt       time.Time
+
+	// Number of jobs of a job array and index of the first one (the job itself is not executed)
+	ArraySize  uint32
+	ArrayStart int
+	// Reference of the job array of a job created by an array and its index in the array
+	ArrayParent string
+	ArrayIndex  int
+	// Number of jobs of a job array in each state
+	ArrayStateCount map[JobState]uint32
 }
 
-// Get the reference `Id-Term` of the job
+// Get the reference `Id-Term` of the job, or `Id-Term_Index` for a job created by a job array
 func (job *Job) GetReference() string {
+	if job.IsArrayChild() {
+		return fmt.Sprintf("%s_%d", job.ArrayParent, job.ArrayIndex)
+	}
 	return fmt.Sprintf("%d-%d", job.Id, job.Term)
 }
 
+// IsArray returns true if the job is a job array
+func (job *Job) IsArray() bool {
+	return job.ArraySize > 0
+}
+
+// HasArchive returns true if the job is a multi-file job, whose archive may be in a blob store
+func (job *Job) HasArchive() bool {
+	_, isStored := job.Blobs[ArchiveBlobField]
+	return job.Archive != nil || isStored
+}
+
+// IsArrayChild returns true if the job has been created by a job array
+func (job *Job) IsArrayChild() bool {
+	return job.ArrayParent != ""
+}
+
+// GetEnvList returns the environment variables of the job in the form `KEY=VALUE`, sorted by key
+func (job *Job) GetEnvList() []string {
+	envList := make([]string, 0, len(job.Env)+2)
+	for key, value := range job.Env {
+		envList = append(envList, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(envList)
+	if job.IsArrayChild() {
+		envList = append(envList,
+			fmt.Sprintf("JOB_ARRAY_PARENT=%s", job.ArrayParent),
+			fmt.Sprintf("JOB_ARRAY_INDEX=%d", job.ArrayIndex),
+		)
+	}
+	return envList
+}
+
+// GetLanguage returns the language of the job, or the default language if it is not given
+func (job *Job) GetLanguage() string {
+	if job.Language == "" {
+		return DEFAULT_LANGUAGE
+	}
+	return job.Language
+}
+
+// GetCompiler returns the compiler of the job, or the default compiler if it is not given
+func (job *Job) GetCompiler() string {
+	if job.Compiler == "" {
+		return DEFAULT_COMPILER
+	}
+	return job.Compiler
+}
+
+// CheckCompilerOptions checks that the compiler and the compiler flags of the job are allowed on the
+// workers
+func (job *Job) CheckCompilerOptions() error {
+	compiler := job.GetCompiler()
+	isAllowedCompiler := false
+	for _, allowedCompiler := range Config.CompilerList {
+		if compiler == allowedCompiler {
+			isAllowedCompiler = true
+			break
+		}
+	}
+	if !isAllowedCompiler {
+		return fmt.Errorf("The compiler %s is not allowed. It must be one of: %s", compiler, strings.Join(Config.CompilerList, ", "))
+	}
+
+	for _, compilerFlag := range job.CompilerFlags {
+		isAllowedFlag := false
+		for _, pattern := range Config.CompilerFlagPatternList {
+			if matchCompilerFlag(pattern, compilerFlag) {
+				isAllowedFlag = true
+				break
+			}
+		}
+		if !isAllowedFlag {
+			return fmt.Errorf("The compiler flag %s is not allowed", compilerFlag)
+		}
+	}
+	return nil
+}
+
+// matchCompilerFlag checks if a compiler flag matches a pattern of path.Match. The slashes are
+// replaced so that `*` also matches them, for example `-DPATH="/usr/lib"` matches `-D*`.
+func matchCompilerFlag(pattern string, compilerFlag string) bool {
+	const slashReplacement = "\x00"
+	matched, _ := path.Match(strings.ReplaceAll(pattern, "/", slashReplacement), strings.ReplaceAll(compilerFlag, "/", slashReplacement))
+	return matched
+}
+
+// GetExecutable returns the path of the executable of a multi-file job in its workspace
+func (job *Job) GetExecutable() string {
+	if job.Executable == "" {
+		return DEFAULT_EXECUTABLE
+	}
+	return job.Executable
+}
+
+// ResetResult clears the result of the previous attempt before the job is executed again
+func (job *Job) ResetResult() {
+	job.ExitCode = NO_EXIT_CODE
+	job.Signal = ""
+	job.CompileLog = ""
+	job.Stdout = ""
+	job.Stderr = ""
+	job.Artifacts = nil
+	job.ArtifactList = nil
+	job.ClearBlobs(JobResultBlobFieldList)
+}
+
+// GetArrayChildReference returns the reference of the job of the given index of a job array
+func (job *Job) GetArrayChildReference(index int) string {
+	return fmt.Sprintf("%s_%d", job.GetReference(), index)
+}
+
+// GetArrayChild creates the job of the given index of a job array
+func (job *Job) GetArrayChild(index int) Job {
+	child := *job
+	child.ArraySize = 0
+	child.ArrayStart = 0
+	child.ArrayStateCount = nil
+	child.ArrayParent = job.GetReference()
+	child.ArrayIndex = index
+	// Spread the jobs of the array on the workers
+	offset := uint32(index-job.ArrayStart) % Config.WorkerNodeCount
+	child.WorkerId = int((uint32(job.WorkerId) + offset) % Config.WorkerNodeCount)
+	return child
+}
+
+// ParseJobReference parses a job reference `Id-Term` or `Id-Term_Index` and returns a job with this reference
+func ParseJobReference(reference string) (Job, error) {
+	var job Job
+	parentReference, indexToken, isChild := strings.Cut(reference, "_")
+	if _, err := fmt.Sscanf(parentReference, "%d-%d", &job.Id, &job.Term); err != nil || job.GetReference() != parentReference {
+		return Job{}, fmt.Errorf("Invalid job reference: %s", reference)
+	}
+	if isChild {
+		if _, err := fmt.Sscanf(indexToken, "%d", &job.ArrayIndex); err != nil || job.ArrayIndex < 0 {
+			return Job{}, fmt.Errorf("Invalid job reference: %s", reference)
+		}
+		job.ArrayParent = parentReference
+	}
+	return job, nil
+}
+
 /***************
  ** Load Code **
  ***************/
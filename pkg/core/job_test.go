@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+)
+
+// A compiler flag pattern must match the flags containing slashes
+func TestMatchCompilerFlag(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		flag     string
+		expected bool
+	}{
+		{"-O[0-3sgz]", "-O2", true},
+		{"-O[0-3sgz]", "-O4", false},
+		{"-D*", "-DDEBUG", true},
+		{"-D*", `-DPATH="/usr/lib"`, true},
+		{"-std=*", "-std=c++17", true},
+		{"-g", "-ggdb", false},
+		{"-l*", "-fplugin=/tmp/plugin.so", false},
+	}
+	for _, test := range tests {
+		if matched := matchCompilerFlag(test.pattern, test.flag); matched != test.expected {
+			t.Fatalf("matchCompilerFlag(%q, %q) = %v instead of %v", test.pattern, test.flag, matched, test.expected)
+		}
+	}
+}
+
+// ParseJobReference must accept the references of jobs and of the jobs of job arrays only
+func TestParseJobReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		valid     bool
+	}{
+		{"1-2", true},
+		{"10-3_4", true},
+		{"1", false},
+		{"1-", false},
+		{"01-2", false},
+		{"1-2_", false},
+		{"1-2_-1", false},
+		{"a-b", false},
+	}
+	for _, test := range tests {
+		job, err := ParseJobReference(test.reference)
+		if test.valid && (err != nil || job.GetReference() != test.reference) {
+			t.Fatalf("ParseJobReference(%q) = %s, %v", test.reference, job.GetReference(), err)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("ParseJobReference(%q) = %s instead of an error", test.reference, job.GetReference())
+		}
+	}
+}
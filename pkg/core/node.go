@@ -39,7 +39,13 @@ type ChannelContainer struct {
 	RequestVote  chan RequestVoteRPC
 	ResponseVote chan ResponseVoteRPC
 
-	JobQueue chan Job
+	JobQueue *JobQueue
+	// References of the jobs to cancel on a worker
+	CancelJob chan string
+	// Requests for the contents of the blob store of the node
+	RequestBlob chan BlobRequest
+	// Requests to follow the outputs of the job running on a worker
+	SubscribeOutput chan OutputSubscription
 }
 
 /***************
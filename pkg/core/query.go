@@ -0,0 +1,236 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/********************
+ ** Job Sort Field **
+ ********************/
+
+// JobSortField is the field by which the jobs listed by a StatusCommand are sorted
+type JobSortField string
+
+const (
+	SortByReference  JobSortField = "reference"
+	SortBySubmission JobSortField = "submitted"
+	SortByState      JobSortField = "state"
+	SortByPriority   JobSortField = "priority"
+	SortByWorker     JobSortField = "worker"
+)
+
+var JobSortFieldList = []JobSortField{SortByReference, SortBySubmission, SortByState, SortByPriority, SortByWorker}
+
+// ParseJobSortField converts a string to a JobSortField
+func ParseJobSortField(token string) (JobSortField, error) {
+	for _, field := range JobSortFieldList {
+		if strings.ToLower(token) == string(field) {
+			return field, nil
+		}
+	}
+	return SortByReference, fmt.Errorf("Invalid sort field: %s", token)
+}
+
+// ParseJobState converts a string (waiting, succeeded, ...) to a JobState
+func ParseJobState(token string) (JobState, error) {
+	for _, state := range []JobState{JobWaiting, JobSucceeded, JobFailed, JobSkipped, JobCancelled} {
+		if strings.ToUpper(token) == state.String() {
+			return state, nil
+		}
+	}
+	return JobWaiting, fmt.Errorf("Invalid state: %s", token)
+}
+
+/***************
+ ** Job Query **
+ ***************/
+
+// JobQuery selects, sorts and paginates the jobs listed by a StatusCommand. An empty filter selects
+// all the jobs.
+type JobQuery struct {
+	States    []JobState
+	WorkerIds []int
+	Labels    map[string]string
+	// Ids of the clients which submitted the jobs
+	SubmitterIds []uint32
+	// Range of submission times (no limit if zero)
+	SubmittedAfter  time.Time
+	SubmittedBefore time.Time
+	// List the jobs of the job arrays, which are summarized by their array otherwise
+	ArrayJobs bool
+
+	SortBy     JobSortField
+	Descending bool
+	// Number of matching jobs skipped and maximum number of jobs listed (Config.StatusPageSize if 0)
+	Offset uint32
+	Limit  uint32
+}
+
+// Matches checks if a job is selected by the query
+func (query JobQuery) Matches(job Job) bool {
+	if job.IsArrayChild() && !query.ArrayJobs {
+		return false
+	}
+	if len(query.States) > 0 && !containsJobState(query.States, job.State) {
+		return false
+	}
+	if len(query.WorkerIds) > 0 && !containsInt(query.WorkerIds, job.WorkerId) {
+		return false
+	}
+	for key, value := range query.Labels {
+		if jobValue, ok := job.Labels[key]; !ok || jobValue != value {
+			return false
+		}
+	}
+	if len(query.SubmitterIds) > 0 && (job.Submitter.Type != ClientNodeType || !containsUint32(query.SubmitterIds, job.Submitter.Id)) {
+		return false
+	}
+	if !query.SubmittedAfter.IsZero() && job.SubmittedAt.Before(query.SubmittedAfter) {
+		return false
+	}
+	if !query.SubmittedBefore.IsZero() && !job.SubmittedAt.Before(query.SubmittedBefore) {
+		return false
+	}
+	return true
+}
+
+// Less checks if a job is listed before another one. The jobs are sorted by reference when the
+// sort field is equal.
+func (query JobQuery) Less(job Job, other Job) bool {
+	var compare int
+	switch query.SortBy {
+	case SortBySubmission:
+		compare = compareTime(job.SubmittedAt, other.SubmittedAt)
+	case SortByState:
+		compare = compareInt(int(job.State), int(other.State))
+	case SortByPriority:
+		compare = compareInt(int(job.Priority), int(other.Priority))
+	case SortByWorker:
+		compare = compareInt(job.WorkerId, other.WorkerId)
+	}
+	if compare == 0 {
+		compare = compareJobReferences(job, other)
+	}
+	if query.Descending {
+		return compare > 0
+	}
+	return compare < 0
+}
+
+// GetLimit returns the maximum number of jobs listed
+func (query JobQuery) GetLimit() uint32 {
+	if query.Limit == 0 {
+		return Config.StatusPageSize
+	}
+	return query.Limit
+}
+
+// compareJobReferences compares the references of two jobs in the order of their submission: by
+// term first, since the job ids start again at each term
+func compareJobReferences(job Job, other Job) int {
+	if compare := compareInt(int(job.Term), int(other.Term)); compare != 0 {
+		return compare
+	}
+	if compare := compareInt(int(job.Id), int(other.Id)); compare != 0 {
+		return compare
+	}
+	return compareInt(job.ArrayIndex, other.ArrayIndex)
+}
+
+func compareInt(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareTime(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func containsJobState(stateList []JobState, state JobState) bool {
+	for _, item := range stateList {
+		if item == state {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(list []int, value int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUint32(list []uint32, value uint32) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+/*****************
+ ** Job Summary **
+ *****************/
+
+// JobSummary contains the fields of a job listed by a StatusCommand, without its inputs and outputs
+type JobSummary struct {
+	Reference         string
+	State             JobState
+	WorkerId          int
+	Priority          JobPriority
+	Labels            map[string]string
+	Submitter         NodeCard
+	SubmittedAt       time.Time
+	ScheduleReference string
+	AttemptCount      int
+	ExitCode          int
+	Signal            string
+	// Number of jobs of a job array and number of them which have ended
+	ArraySize       uint32
+	ArrayEndedCount uint32
+}
+
+// GetSummary returns the summary of the job
+func (job *Job) GetSummary() JobSummary {
+	summary := JobSummary{
+		Reference:         job.GetReference(),
+		State:             job.State,
+		WorkerId:          job.WorkerId,
+		Priority:          job.Priority,
+		Labels:            job.Labels,
+		Submitter:         job.Submitter,
+		SubmittedAt:       job.SubmittedAt,
+		ScheduleReference: job.ScheduleReference,
+		AttemptCount:      len(job.Attempts),
+		ExitCode:          job.ExitCode,
+		Signal:            job.Signal,
+		ArraySize:         job.ArraySize,
+	}
+	if job.IsArray() && job.ArrayStateCount != nil {
+		summary.ArrayEndedCount = job.ArraySize - job.ArrayStateCount[JobWaiting]
+	}
+	return summary
+}
+
+// IsArray returns true if the summary is the one of a job array
+func (summary JobSummary) IsArray() bool {
+	return summary.ArraySize > 0
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// The jobs must be sorted by the sort field, then by term, id and array index
+func TestJobQueryLess(t *testing.T) {
+	submittedAt := time.Date(2024, time.January, 10, 10, 0, 0, 0, time.UTC)
+	jobList := []Job{
+		{Id: 2, Term: 2, State: JobWaiting, Priority: LowPriority, WorkerId: 1, SubmittedAt: submittedAt.Add(3 * time.Second)},
+		{Id: 10, Term: 1, State: JobFailed, Priority: HighPriority, WorkerId: 0, SubmittedAt: submittedAt.Add(2 * time.Second)},
+		{Id: 1, Term: 2, State: JobSucceeded, Priority: HighPriority, WorkerId: 0, SubmittedAt: submittedAt.Add(time.Second)},
+		{Id: 9, Term: 1, State: JobWaiting, Priority: MediumPriority, WorkerId: 1, SubmittedAt: submittedAt},
+	}
+
+	tests := []struct {
+		query    JobQuery
+		expected []string
+	}{
+		{JobQuery{SortBy: SortByReference}, []string{"9-1", "10-1", "1-2", "2-2"}},
+		{JobQuery{SortBy: SortByReference, Descending: true}, []string{"2-2", "1-2", "10-1", "9-1"}},
+		{JobQuery{SortBy: SortBySubmission}, []string{"9-1", "1-2", "10-1", "2-2"}},
+		{JobQuery{SortBy: SortByState}, []string{"9-1", "2-2", "1-2", "10-1"}},
+		{JobQuery{SortBy: SortByPriority, Descending: true}, []string{"1-2", "10-1", "9-1", "2-2"}},
+		{JobQuery{SortBy: SortByWorker}, []string{"10-1", "1-2", "9-1", "2-2"}},
+	}
+	for _, test := range tests {
+		sortedList := append([]Job(nil), jobList...)
+		sort.Slice(sortedList, func(i, j int) bool { return test.query.Less(sortedList[i], sortedList[j]) })
+		for i, job := range sortedList {
+			if job.GetReference() != test.expected[i] {
+				t.Fatalf("job %s listed at position %d instead of job %s when sorted by %s", job.GetReference(), i, test.expected[i], test.query.SortBy)
+			}
+		}
+	}
+}
+
+// The jobs of a job array must be sorted by index
+func TestJobQueryLessArrayIndex(t *testing.T) {
+	query := JobQuery{SortBy: SortByReference}
+	first := Job{Id: 1, Term: 1, ArrayParent: "1-1", ArrayIndex: 2}
+	second := Job{Id: 1, Term: 1, ArrayParent: "1-1", ArrayIndex: 10}
+	if !query.Less(first, second) || query.Less(second, first) {
+		t.Fatal("the jobs of a job array are not sorted by index")
+	}
+}
+
+// Matches must select the jobs matching all the filters of the query
+func TestJobQueryMatches(t *testing.T) {
+	submittedAt := time.Date(2024, time.January, 10, 10, 0, 0, 0, time.UTC)
+	job := Job{
+		Id:          1,
+		Term:        1,
+		State:       JobFailed,
+		WorkerId:    1,
+		Labels:      map[string]string{"team": "data", "env": "prod"},
+		Submitter:   NodeCard{Type: ClientNodeType, Id: 3},
+		SubmittedAt: submittedAt,
+	}
+	child := Job{Id: 2, Term: 1, ArrayParent: "2-1", ArrayIndex: 0}
+
+	tests := []struct {
+		name     string
+		query    JobQuery
+		job      Job
+		expected bool
+	}{
+		{"empty query", JobQuery{}, job, true},
+		{"state", JobQuery{States: []JobState{JobSucceeded, JobFailed}}, job, true},
+		{"other state", JobQuery{States: []JobState{JobWaiting}}, job, false},
+		{"worker", JobQuery{WorkerIds: []int{0, 1}}, job, true},
+		{"other worker", JobQuery{WorkerIds: []int{0}}, job, false},
+		{"label", JobQuery{Labels: map[string]string{"team": "data"}}, job, true},
+		{"other label value", JobQuery{Labels: map[string]string{"team": "web"}}, job, false},
+		{"missing label", JobQuery{Labels: map[string]string{"owner": "data"}}, job, false},
+		{"submitter", JobQuery{SubmitterIds: []uint32{3}}, job, true},
+		{"other submitter", JobQuery{SubmitterIds: []uint32{4}}, job, false},
+		{"submitted after", JobQuery{SubmittedAfter: submittedAt}, job, true},
+		{"submitted before", JobQuery{SubmittedBefore: submittedAt}, job, false},
+		{"array job hidden", JobQuery{}, child, false},
+		{"array job listed", JobQuery{ArrayJobs: true}, child, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if matches := test.query.Matches(test.job); matches != test.expected {
+				t.Fatalf("Matches = %v instead of %v", matches, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+/***************
+ ** Job Queue **
+ ***************/
+
+// jobQueueItem is a job waiting in a JobQueue with its arrival order
+type jobQueueItem struct {
+	job      Job
+	sequence int64
+}
+
+// jobHeap implements heap.Interface to order the jobs of a JobQueue
+type jobHeap struct {
+	items []jobQueueItem
+}
+
+func (h *jobHeap) Len() int { return len(h.items) }
+
+func (h *jobHeap) Less(i, j int) bool {
+	if h.items[i].job.Priority != h.items[j].job.Priority {
+		return h.items[i].job.Priority > h.items[j].job.Priority
+	}
+	return h.items[i].sequence < h.items[j].sequence
+}
+
+func (h *jobHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *jobHeap) Push(x any) { h.items = append(h.items, x.(jobQueueItem)) }
+
+func (h *jobHeap) Pop() any {
+	last := len(h.items) - 1
+	item := h.items[last]
+	h.items = h.items[:last]
+	return item
+}
+
+// JobQueue is the queue of jobs waiting to be executed by a worker. It is safe for concurrent use.
+// A job with a higher priority is executed before the queued jobs with a lower priority, and the
+// queue is a FIFO between jobs of the same priority.
+type JobQueue struct {
+	mutex sync.Mutex
+	// Wakes the waiters up when a job is pushed
+	pushed *sync.Cond
+	heap   jobHeap
+	// Sequences of the last job pushed at the back and at the front of the queue
+	backSequence  int64
+	frontSequence int64
+}
+
+// NewJobQueue creates an empty job queue
+func NewJobQueue() *JobQueue {
+	queue := &JobQueue{}
+	queue.pushed = sync.NewCond(&queue.mutex)
+	return queue
+}
+
+// Push adds a job to the queue, after the queued jobs of the same priority
+func (queue *JobQueue) Push(job Job) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.backSequence++
+	heap.Push(&queue.heap, jobQueueItem{job: job, sequence: queue.backSequence})
+	queue.pushed.Broadcast()
+}
+
+// PushFront adds a job to the queue, before the queued jobs of the same priority. It is used to put
+// a preempted job back in the queue.
+func (queue *JobQueue) PushFront(job Job) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.frontSequence--
+	heap.Push(&queue.heap, jobQueueItem{job: job, sequence: queue.frontSequence})
+	queue.pushed.Broadcast()
+}
+
+// Pop removes and returns the next job to execute. It blocks until a job is available.
+func (queue *JobQueue) Pop() Job {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	for queue.heap.Len() == 0 {
+		queue.pushed.Wait()
+	}
+	return heap.Pop(&queue.heap).(jobQueueItem).job
+}
+
+// WaitForHigherPriority blocks until a job with a higher priority than the given one is queued and
+// returns true, or until the context is done and returns false
+func (queue *JobQueue) WaitForHigherPriority(ctx context.Context, priority JobPriority) bool {
+	go func() {
+		<-ctx.Done()
+		queue.mutex.Lock()
+		queue.pushed.Broadcast()
+		queue.mutex.Unlock()
+	}()
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	for ctx.Err() == nil {
+		if queue.heap.Len() > 0 && queue.heap.items[0].job.Priority > priority {
+			return true
+		}
+		queue.pushed.Wait()
+	}
+	return false
+}
+
+// Len returns the number of jobs in the queue
+func (queue *JobQueue) Len() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.heap.Len()
+}
+
+// Remove removes a job from the queue. It returns false if the job is not in the queue.
+func (queue *JobQueue) Remove(reference string) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	for i, item := range queue.heap.items {
+		if item.job.GetReference() == reference {
+			heap.Remove(&queue.heap, i)
+			return true
+		}
+	}
+	return false
+}
+
+// Contains checks if a job is in the queue
+func (queue *JobQueue) Contains(reference string) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	for _, item := range queue.heap.items {
+		if item.job.GetReference() == reference {
+			return true
+		}
+	}
+	return false
+}
+
+// CountAhead returns the number of queued jobs that will be executed before a new job with the given priority
+func (queue *JobQueue) CountAhead(priority JobPriority) int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	count := 0
+	for _, item := range queue.heap.items {
+		if item.job.Priority >= priority {
+			count++
+		}
+	}
+	return count
+}
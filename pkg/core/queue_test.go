@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// queueTestJob returns a job with the given id and priority
+func queueTestJob(id uint32, priority JobPriority) Job {
+	return Job{Id: id, Term: 1, Priority: priority}
+}
+
+// The jobs must be popped by priority, in the order of their arrival within a priority, and a job
+// pushed at the front must be popped before the other jobs of its priority
+func TestJobQueueOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		push      []Job
+		pushFront []Job
+		expected  []uint32
+	}{
+		{
+			name:     "fifo within a priority",
+			push:     []Job{queueTestJob(1, MediumPriority), queueTestJob(2, MediumPriority), queueTestJob(3, MediumPriority)},
+			expected: []uint32{1, 2, 3},
+		},
+		{
+			name:     "higher priority first",
+			push:     []Job{queueTestJob(1, LowPriority), queueTestJob(2, MediumPriority), queueTestJob(3, HighPriority), queueTestJob(4, MediumPriority)},
+			expected: []uint32{3, 2, 4, 1},
+		},
+		{
+			name:      "preempted job first within its priority",
+			push:      []Job{queueTestJob(1, MediumPriority), queueTestJob(2, HighPriority)},
+			pushFront: []Job{queueTestJob(3, MediumPriority), queueTestJob(4, MediumPriority)},
+			expected:  []uint32{2, 4, 3, 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			queue := NewJobQueue()
+			for _, job := range test.push {
+				queue.Push(job)
+			}
+			for _, job := range test.pushFront {
+				queue.PushFront(job)
+			}
+			for _, id := range test.expected {
+				if job := queue.Pop(); job.Id != id {
+					t.Fatalf("job %d popped instead of job %d", job.Id, id)
+				}
+			}
+			if queue.Len() != 0 {
+				t.Fatalf("%d jobs left in the queue", queue.Len())
+			}
+		})
+	}
+}
+
+// A removed job must not be popped, and a job which is not queued cannot be removed
+func TestJobQueueRemove(t *testing.T) {
+	queue := NewJobQueue()
+	for id := uint32(1); id <= 3; id++ {
+		queue.Push(queueTestJob(id, MediumPriority))
+	}
+	job := queueTestJob(2, MediumPriority)
+	if !queue.Contains(job.GetReference()) {
+		t.Fatalf("the job %s is not in the queue", job.GetReference())
+	}
+	if !queue.Remove(job.GetReference()) {
+		t.Fatalf("the job %s has not been removed", job.GetReference())
+	}
+	if queue.Contains(job.GetReference()) || queue.Remove(job.GetReference()) {
+		t.Fatalf("the job %s is still in the queue", job.GetReference())
+	}
+	for _, id := range []uint32{1, 3} {
+		if job := queue.Pop(); job.Id != id {
+			t.Fatalf("job %d popped instead of job %d", job.Id, id)
+		}
+	}
+}
+
+// The jobs counted ahead of a new job are the queued jobs of the same or a higher priority
+func TestJobQueueCountAhead(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Push(queueTestJob(1, LowPriority))
+	queue.Push(queueTestJob(2, MediumPriority))
+	queue.Push(queueTestJob(3, MediumPriority))
+	queue.Push(queueTestJob(4, HighPriority))
+
+	tests := []struct {
+		priority JobPriority
+		expected int
+	}{
+		{LowPriority, 4},
+		{MediumPriority, 3},
+		{HighPriority, 1},
+	}
+	for _, test := range tests {
+		if count := queue.CountAhead(test.priority); count != test.expected {
+			t.Fatalf("%d jobs ahead of a %s job instead of %d", count, test.priority, test.expected)
+		}
+	}
+}
+
+// A running job must only be preempted by a queued job of a higher priority
+func TestJobQueueWaitForHigherPriority(t *testing.T) {
+	queue := NewJobQueue()
+	queue.Push(queueTestJob(1, MediumPriority))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if queue.WaitForHigherPriority(ctx, MediumPriority) {
+		t.Fatal("a job of the same priority preempts the running job")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go queue.Push(queueTestJob(2, HighPriority))
+	if !queue.WaitForHigherPriority(ctx, MediumPriority) {
+		t.Fatal("a job of a higher priority does not preempt the running job")
+	}
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Exit code of an attempt when the job could not be compiled or started
+const NO_EXIT_CODE = -1
+
+// Maximum number of times the delay is doubled with an exponential backoff
+const maxBackoffShift = 16
+
+// Maximum delay before a retry
+const maxRetryDelay = 24 * time.Hour
+
+/******************
+ ** Backoff Type **
+ ******************/
+
+type BackoffType int
+
+const (
+	FixedBackoff BackoffType = iota
+	ExponentialBackoff
+)
+
+// Convert a BackoffType to a string
+func (b BackoffType) String() string {
+	return [...]string{"fixed", "exponential"}[b]
+}
+
+// ParseBackoffType converts a string (fixed or exponential) to a BackoffType
+func ParseBackoffType(token string) (BackoffType, error) {
+	for _, backoff := range []BackoffType{FixedBackoff, ExponentialBackoff} {
+		if strings.ToLower(token) == backoff.String() {
+			return backoff, nil
+		}
+	}
+	return FixedBackoff, fmt.Errorf("Invalid backoff: %s", token)
+}
+
+/******************
+ ** Retry Policy **
+ ******************/
+
+// RetryPolicy describes when and how a failed job is executed again
+type RetryPolicy struct {
+	// Maximum number of executions of the job (0 or 1 means that the job is never retried)
+	MaxAttempts uint32
+	Backoff     BackoffType
+	// Delay before the first retry. It is doubled at each retry with an exponential backoff.
+	Delay time.Duration
+	// Exit codes for which the job is retried (all non-zero exit codes if empty)
+	RetryOnExitCodes []int
+	RetryOnTimeout   bool
+	// Retry the job when it is killed by a signal, for example when it exceeds its memory limit
+	RetryOnSignal bool
+}
+
+// ShouldRetry checks if a failed attempt can be retried according to the policy
+func (policy *RetryPolicy) ShouldRetry(attempt JobAttempt) bool {
+	if attempt.Number >= policy.MaxAttempts {
+		return false
+	}
+	if attempt.TimedOut {
+		return policy.RetryOnTimeout
+	}
+	if attempt.Signal != "" {
+		return policy.RetryOnSignal
+	}
+	if attempt.ExitCode == 0 || attempt.ExitCode == NO_EXIT_CODE {
+		return false
+	}
+	if len(policy.RetryOnExitCodes) == 0 {
+		return true
+	}
+	for _, exitCode := range policy.RetryOnExitCodes {
+		if exitCode == attempt.ExitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// NextDelay returns the delay to wait after the given attempt before the next one, at most
+// maxRetryDelay
+func (policy *RetryPolicy) NextDelay(attemptNumber uint32) time.Duration {
+	if policy.Delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+	if policy.Backoff == FixedBackoff || attemptNumber <= 1 {
+		return policy.Delay
+	}
+	shift := attemptNumber - 1
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	if policy.Delay > maxRetryDelay>>shift {
+		return maxRetryDelay
+	}
+	return policy.Delay << shift
+}
+
+// String returns a human readable representation of the policy
+func (policy RetryPolicy) String() string {
+	if policy.MaxAttempts <= 1 {
+		return "none"
+	}
+	description := fmt.Sprintf("%d attempts, %s backoff of %v", policy.MaxAttempts, policy.Backoff, policy.Delay)
+	if len(policy.RetryOnExitCodes) > 0 {
+		description += fmt.Sprintf(", on exit codes %v", policy.RetryOnExitCodes)
+	}
+	if policy.RetryOnTimeout {
+		description += ", on timeout"
+	}
+	if policy.RetryOnSignal {
+		description += ", on signal"
+	}
+	return description
+}
+
+/*****************
+ ** Job Attempt **
+ *****************/
+
+// JobAttempt is the result of one execution of a job by a worker
+type JobAttempt struct {
+	Number     uint32
+	WorkerId   int
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Exit code of the job, or NO_EXIT_CODE if it could not be compiled or started or if it has been
+	// killed by a signal
+	ExitCode int
+	// Name of the signal which killed the job, empty if it has exited
+	Signal   string
+	TimedOut bool
+	Error    string
+	// The binary of the job was found in the compile cache of the worker
+	CompileCacheHit bool
+	// Workspace of the attempt on the worker, only when the workspaces are kept for debugging
+	Workspace string
+	// Resources used by the execution of the job
+	Usage ResourceUsage
+}
+
+// String returns a human readable representation of the attempt
+func (attempt JobAttempt) String() string {
+	result := fmt.Sprintf("#%d | Worker %d | %v | exit code %d", attempt.Number, attempt.WorkerId,
+		attempt.FinishedAt.Sub(attempt.StartedAt).Round(time.Millisecond), attempt.ExitCode)
+	if attempt.CompileCacheHit {
+		result += " | cached build"
+	}
+	if attempt.Usage.IsMeasured() {
+		result += " | " + attempt.Usage.String()
+	}
+	if attempt.Signal != "" {
+		result += " | signal " + attempt.Signal
+	}
+	if attempt.TimedOut {
+		result += " | timed out"
+	}
+	if attempt.Error != "" {
+		result += " | " + attempt.Error
+	}
+	if attempt.Workspace != "" {
+		result += " | workspace " + attempt.Workspace
+	}
+	return result
+}
+
+/***************
+ ** Job Retry **
+ ***************/
+
+// PrepareRetry sets a failed job back to WAITING if its last attempt can be retried.
+// RetryAt is computed from the end of the last attempt so that all the nodes get the same value.
+func (job *Job) PrepareRetry() bool {
+	if job.State != JobFailed || len(job.Attempts) == 0 {
+		return false
+	}
+	lastAttempt := job.Attempts[len(job.Attempts)-1]
+	if !job.RetryPolicy.ShouldRetry(lastAttempt) {
+		return false
+	}
+	job.State = JobWaiting
+	job.RetryAt = lastAttempt.FinishedAt.Add(job.RetryPolicy.NextDelay(lastAttempt.Number))
+	return true
+}
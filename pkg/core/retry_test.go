@@ -0,0 +1,59 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// A failed attempt must be retried according to the exit codes, timeout and signal of the policy
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  JobAttempt
+		expected bool
+	}{
+		{"no retry", RetryPolicy{MaxAttempts: 1}, JobAttempt{Number: 1, ExitCode: 1}, false},
+		{"non-zero exit code", RetryPolicy{MaxAttempts: 3}, JobAttempt{Number: 1, ExitCode: 1}, true},
+		{"last attempt", RetryPolicy{MaxAttempts: 3}, JobAttempt{Number: 3, ExitCode: 1}, false},
+		{"succeeded", RetryPolicy{MaxAttempts: 3}, JobAttempt{Number: 1, ExitCode: 0}, false},
+		{"not started", RetryPolicy{MaxAttempts: 3}, JobAttempt{Number: 1, ExitCode: NO_EXIT_CODE}, false},
+		{"listed exit code", RetryPolicy{MaxAttempts: 3, RetryOnExitCodes: []int{2, 3}}, JobAttempt{Number: 1, ExitCode: 3}, true},
+		{"unlisted exit code", RetryPolicy{MaxAttempts: 3, RetryOnExitCodes: []int{2, 3}}, JobAttempt{Number: 1, ExitCode: 1}, false},
+		{"timeout", RetryPolicy{MaxAttempts: 3, RetryOnTimeout: true}, JobAttempt{Number: 1, ExitCode: NO_EXIT_CODE, TimedOut: true}, true},
+		{"timeout not retried", RetryPolicy{MaxAttempts: 3}, JobAttempt{Number: 1, ExitCode: NO_EXIT_CODE, TimedOut: true}, false},
+		{"signal", RetryPolicy{MaxAttempts: 3, RetryOnSignal: true}, JobAttempt{Number: 1, ExitCode: NO_EXIT_CODE, Signal: "killed"}, true},
+		{"signal not retried", RetryPolicy{MaxAttempts: 3}, JobAttempt{Number: 1, ExitCode: NO_EXIT_CODE, Signal: "killed"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if retry := test.policy.ShouldRetry(test.attempt); retry != test.expected {
+				t.Fatalf("ShouldRetry(%v) = %v instead of %v", test.attempt, retry, test.expected)
+			}
+		})
+	}
+}
+
+// The delay must be doubled at each attempt with an exponential backoff, without exceeding maxRetryDelay
+func TestRetryPolicyNextDelay(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        RetryPolicy
+		attemptNumber uint32
+		expected      time.Duration
+	}{
+		{"fixed", RetryPolicy{Backoff: FixedBackoff, Delay: time.Second}, 5, time.Second},
+		{"exponential first", RetryPolicy{Backoff: ExponentialBackoff, Delay: time.Second}, 1, time.Second},
+		{"exponential third", RetryPolicy{Backoff: ExponentialBackoff, Delay: time.Second}, 3, 4 * time.Second},
+		{"exponential clamped", RetryPolicy{Backoff: ExponentialBackoff, Delay: time.Hour}, 10, maxRetryDelay},
+		{"exponential overflow", RetryPolicy{Backoff: ExponentialBackoff, Delay: 1 << 60}, 100, maxRetryDelay},
+		{"fixed clamped", RetryPolicy{Backoff: FixedBackoff, Delay: 48 * time.Hour}, 1, maxRetryDelay},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if delay := test.policy.NextDelay(test.attemptNumber); delay != test.expected {
+				t.Fatalf("NextDelay(%d) = %v instead of %v", test.attemptNumber, delay, test.expected)
+			}
+		})
+	}
+}
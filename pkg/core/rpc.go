@@ -1,5 +1,7 @@
 package core
 
+import "time"
+
 // Generic RPC Type
 type RPCType interface {
 	RequestCommandRPC | ResponseCommandRPC | RequestVoteRPC | ResponseVoteRPC
@@ -19,11 +21,13 @@ const (
 	CrashCommand
 	RecoverCommand
 	StatusCommand
+	WaitCommand
+	WatchCommand
 )
 
 // Convert a CommandType to a string
 func (c CommandType) String() string {
-	return [...]string{"Synchronize", "AppendEntry", "Start", "Crash", "Recover", "Status"}[c]
+	return [...]string{"Synchronize", "AppendEntry", "Start", "Crash", "Recover", "Status", "Wait", "Watch"}[c]
 }
 
 /*****************
@@ -34,6 +38,9 @@ func (c CommandType) String() string {
 type RequestCommandRPC struct {
 	FromNode NodeCard
 	ToNode   NodeCard
+	// Id given by the sender to the request, unique for the sender, and copied in the response so
+	// that a node sending concurrent requests can route each response to its request
+	RequestId uint64
 
 	Term        uint32
 	CommandType CommandType
@@ -42,12 +49,27 @@ type RequestCommandRPC struct {
 	PrevTerm    uint32
 	Entries     []Entry
 	CommitIndex uint32
+
+	// Used for WaitCommand: references of the awaited jobs (all the jobs if empty) and maximum
+	// duration for which the leader holds the request before answering (no limit if 0)
+	JobReferences []string
+	WaitTimeout   time.Duration
+
+	// Used for StatusCommand: jobs listed, or references of the jobs whose details are given instead,
+	// with the jobs of the job arrays
+	JobQuery            JobQuery
+	DetailJobReferences []string
+
+	// Used for WatchCommand
+	Subscription JobSubscription
 }
 
 // ResponseCommandRPC is the RPC used to send a response to a command
 type ResponseCommandRPC struct {
 	FromNode NodeCard
 	ToNode   NodeCard
+	// Id of the request answered
+	RequestId uint64
 
 	Term     uint32
 	LeaderId int
@@ -59,8 +81,70 @@ type ResponseCommandRPC struct {
 	Success    bool
 	MatchIndex uint32
 
-	// Used for StatusCommand
-	JobMap map[string]Job
+	// Used for AppendEntryCommand. JobNotFound is set if the entry has been rejected because its job
+	// does not exist.
+	JobReference      string
+	ScheduleReference string
+	JobNotFound       bool
+
+	// Used for StatusCommand: summaries of a page of the jobs matching the query and number of
+	// matching jobs
+	JobSummaries []JobSummary
+	JobCount     uint32
+
+	// Used for StatusCommand to give the details of jobs, for WaitCommand, and for WatchCommand to give
+	// the watched job
+	JobMap      map[string]Job
+	ScheduleMap map[string]Schedule
+
+	// Used for WaitCommand: all the awaited jobs have ended
+	JobsEnded bool
+
+	// Used for WatchCommand: index of the last entry applied by the leader when the subscription started
+	AppliedIndex uint32
+}
+
+/***************
+ ** Watch RPC **
+ ***************/
+
+// JobSubscription is the interest of a node in the state changes of jobs, registered on the leader
+type JobSubscription struct {
+	// Reference of the watched job, or of the watched job array and its jobs (all the jobs if empty)
+	JobReference string
+	// Labels which the watched jobs must have
+	Labels map[string]string
+	// If Replay is set, the events of the entries already applied after FromIndex are sent first,
+	// for example to resume a subscription ended by a change of leader
+	Replay    bool
+	FromIndex uint32
+	// Channel on which the events are sent, in the order of the log. It is closed when the
+	// subscription ends, always after all the events of an entry.
+	Events chan JobEvent
+	// Channel closed by the subscriber when it stops watching
+	Done chan struct{}
+}
+
+// Matches checks if a job is watched by the subscription
+func (subscription JobSubscription) Matches(job Job) bool {
+	if subscription.JobReference != "" && job.GetReference() != subscription.JobReference && job.ArrayParent != subscription.JobReference {
+		return false
+	}
+	for key, value := range subscription.Labels {
+		if jobValue, ok := job.Labels[key]; !ok || jobValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// JobEvent is a change of a job caused by an entry applied to the state machine of the leader
+type JobEvent struct {
+	// Index of the entry in the log
+	Index     uint32
+	EntryType EntryType
+	// Job after the entry has been applied
+	Job Job
 }
 
 /**************
@@ -86,3 +170,44 @@ type ResponseVoteRPC struct {
 	Term        uint32
 	VoteGranted bool
 }
+
+/**************
+ ** Blob RPC **
+ **************/
+
+// BlobRequest is the request for the content of a blob sent to the node storing it
+type BlobRequest struct {
+	FromNode NodeCard
+	Hash     string
+	// Channel on which the response is sent
+	Response chan BlobResponse
+}
+
+// BlobResponse is the content of a requested blob, or the error if it can not be read
+type BlobResponse struct {
+	Content []byte
+	Error   string
+}
+
+/****************
+ ** Output RPC **
+ ****************/
+
+// OutputSubscription is the request sent to a worker to follow the outputs of a job while it runs
+type OutputSubscription struct {
+	FromNode     NodeCard
+	JobReference string
+	// Channel on which the chunks of the outputs are sent. It is closed when the attempt ends, or
+	// immediately if the job is not running on the worker.
+	Chunks chan OutputChunk
+}
+
+// OutputChunk is a part of the standard output or error of a running job. The first chunk sent to a
+// subscriber only gives the attempt being followed.
+type OutputChunk struct {
+	Attempt uint32
+	Stderr  bool
+	Data    []byte
+	// Number of bytes not sent before this chunk because the subscriber did not read them fast enough
+	SkippedSize uint64
+}
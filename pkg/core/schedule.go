@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+/**************
+ ** Schedule **
+ **************/
+
+// Schedule creates jobs from a template at a given time (SUBMIT --at) or periodically (SCHEDULE)
+type Schedule struct {
+	Id   uint32
+	Term uint32
+	// Cron expression of a recurring schedule, empty for a schedule which runs only once
+	Cron    string
+	NextRun time.Time
+	// Template of the jobs created by the schedule
+	Job Job
+}
+
+// Get the reference `S<Id>-<Term>` of the schedule
+func (schedule *Schedule) GetReference() string {
+	return fmt.Sprintf("S%d-%d", schedule.Id, schedule.Term)
+}
+
+// IsRecurring returns true if the schedule creates a job periodically
+func (schedule *Schedule) IsRecurring() bool {
+	return schedule.Cron != ""
+}
+
+// Advance computes the next run of the schedule after a job has been created at the given time.
+// It returns false if the schedule will not create any other job.
+func (schedule *Schedule) Advance(after time.Time) bool {
+	if !schedule.IsRecurring() {
+		return false
+	}
+	cron, err := ParseCronExpression(schedule.Cron)
+	if err != nil {
+		return false
+	}
+	schedule.NextRun = cron.Next(after)
+	return !schedule.NextRun.IsZero()
+}
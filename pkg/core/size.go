@@ -0,0 +1,55 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Units accepted for a memory size, from the largest to the smallest
+var memoryUnitList = []struct {
+	suffix string
+	size   uint64
+}{
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseMemorySize parses a memory size such as `512K`, `256M` or `1G` and returns it in bytes.
+// A size without unit is in bytes.
+func ParseMemorySize(token string) (uint64, error) {
+	number := strings.ToUpper(strings.TrimSpace(token))
+	// `KB`, `MB` and `GB` are accepted as well
+	if len(number) > 2 && strings.HasSuffix(number, "B") && strings.ContainsAny(number[len(number)-2:len(number)-1], "KMG") {
+		number = strings.TrimSuffix(number, "B")
+	}
+	unit := uint64(1)
+	for _, memoryUnit := range memoryUnitList {
+		if strings.HasSuffix(number, memoryUnit.suffix) {
+			number = strings.TrimSuffix(number, memoryUnit.suffix)
+			unit = memoryUnit.size
+			break
+		}
+	}
+	size, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid memory size: %s", token)
+	}
+	if size > math.MaxUint64/unit {
+		return 0, fmt.Errorf("The memory size %s is too large", token)
+	}
+	return size * unit, nil
+}
+
+// FormatMemorySize converts a memory size in bytes to a string with the largest exact unit
+func FormatMemorySize(size uint64) string {
+	for _, memoryUnit := range memoryUnitList {
+		if size >= memoryUnit.size && size%memoryUnit.size == 0 {
+			return fmt.Sprintf("%d%s", size/memoryUnit.size, memoryUnit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
@@ -0,0 +1,38 @@
+package core
+
+import (
+	"testing"
+)
+
+// ParseMemorySize must accept the units with or without `B` and reject the sizes which overflow
+func TestParseMemorySize(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected uint64
+		valid    bool
+	}{
+		{"1024", 1024, true},
+		{"512B", 512, true},
+		{"512K", 512 << 10, true},
+		{"256mb", 256 << 20, true},
+		{" 1G ", 1 << 30, true},
+		{"16GB", 16 << 30, true},
+		{"", 0, false},
+		{"G", 0, false},
+		{"-1M", 0, false},
+		{"1.5G", 0, false},
+		{"1T", 0, false},
+		{"18446744073709551615", 18446744073709551615, true},
+		{"18446744073709551615K", 0, false},
+		{"17179869184G", 0, false},
+	}
+	for _, test := range tests {
+		size, err := ParseMemorySize(test.token)
+		if test.valid && (err != nil || size != test.expected) {
+			t.Fatalf("ParseMemorySize(%q) = %d, %v instead of %d", test.token, size, err, test.expected)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("ParseMemorySize(%q) = %d instead of an error", test.token, size)
+		}
+	}
+}
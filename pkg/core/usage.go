@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+/********************
+ ** Resource Usage **
+ ********************/
+
+// ResourceUsage is the resources used by the execution of a job, measured by its cgroup on the
+// worker or by the kernel accounting of its process if cgroups are not available
+type ResourceUsage struct {
+	WallTime time.Duration
+	CPUTime  time.Duration
+	// Peak of the memory used by the job in bytes (0 if unknown)
+	PeakMemory uint64
+	// The usage was measured by the cgroup of the job
+	Cgroup bool
+}
+
+// IsMeasured checks if the job has been executed and its usage measured
+func (usage ResourceUsage) IsMeasured() bool {
+	return usage.WallTime > 0
+}
+
+// String returns a human readable representation of the usage
+func (usage ResourceUsage) String() string {
+	result := fmt.Sprintf("wall %v, cpu %v", usage.WallTime.Round(time.Millisecond), usage.CPUTime.Round(time.Millisecond))
+	if usage.PeakMemory > 0 {
+		result += ", peak memory " + formatMemoryUsage(usage.PeakMemory)
+	}
+	return result
+}
+
+// formatMemoryUsage converts a memory size in bytes to a string with the largest unit and one decimal
+func formatMemoryUsage(size uint64) string {
+	for _, memoryUnit := range memoryUnitList {
+		if size >= memoryUnit.size && memoryUnit.size > 1 {
+			return fmt.Sprintf("%.1f%s", float64(size)/float64(memoryUnit.size), memoryUnit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", size)
+}
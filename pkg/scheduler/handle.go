@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/Timelessprod/algorep/pkg/core"
 	"github.com/Timelessprod/algorep/pkg/utils"
@@ -22,6 +23,7 @@ func (node *SchedulerNode) handleRequestSynchronizeCommand(request core.RequestC
 	response := core.ResponseCommandRPC{
 		FromNode:    node.Card,
 		ToNode:      request.FromNode,
+		RequestId:   request.RequestId,
 		Term:        node.CurrentTerm,
 		CommandType: request.CommandType,
 	}
@@ -125,6 +127,7 @@ func (node *SchedulerNode) handleAppendEntryCommand(request core.RequestCommandR
 	response := core.ResponseCommandRPC{
 		FromNode:    node.Card,
 		ToNode:      request.FromNode,
+		RequestId:   request.RequestId,
 		Term:        node.CurrentTerm,
 		CommandType: request.CommandType,
 		LeaderId:    node.LeaderId,
@@ -133,22 +136,60 @@ func (node *SchedulerNode) handleAppendEntryCommand(request core.RequestCommandR
 	if node.State == core.LeaderState {
 		entry := request.Entries[0] // Append only one entry at a time
 
-		logger.Info("I am the leader ! Submit Job.... ",
+		logger.Info("I am the leader ! Submit entry.... ",
 			zap.String("Node", node.Card.String()),
+			zap.String("EntryType", entry.Type.String()),
 			zap.String("JobRef", entry.Job.GetReference()),
+			zap.String("Priority", entry.Job.Priority.String()),
 		)
 
 		entry.Term = node.CurrentTerm
-		if entry.Type == core.OpenJob {
-			entry.Job.WorkerId = int(node.GetWorkerId())
-			entry.Job.Id = node.GetJobId()
-			entry.Job.Term = node.CurrentTerm
-			entry.Job.State = core.JobWaiting
+		var err error
+		switch entry.Type {
+		case core.OpenJob:
+			entry.Job.Submitter = request.FromNode
+			if err = node.prepareJob(&entry.Job); err == nil {
+				response.JobReference = entry.Job.GetReference()
+				response.Message = fmt.Sprintf("Job %s submitted.", response.JobReference)
+				if entry.Job.IsArray() {
+					response.Message = fmt.Sprintf("Job array %s of %d jobs submitted.", response.JobReference, entry.Job.ArraySize)
+				}
+			}
+		case core.CloseJob:
+			response.JobReference = entry.Job.GetReference()
+			response.Message = fmt.Sprintf("Job %s closed.", response.JobReference)
+		case core.CancelJob:
+			response.JobReference = entry.Job.GetReference()
+			if job, ok := node.StateMachine.JobMap[response.JobReference]; !ok {
+				err = fmt.Errorf("Job %s does not exist.", response.JobReference)
+				response.JobNotFound = true
+			} else if job.State.IsTerminal() {
+				err = fmt.Errorf("Job %s is already %s.", response.JobReference, job.State)
+			} else {
+				response.Message = fmt.Sprintf("Job %s cancelled.", response.JobReference)
+			}
+		case core.AddSchedule:
+			entry.Schedule.Job.Submitter = request.FromNode
+			if err = node.prepareSchedule(&entry.Schedule); err == nil {
+				response.ScheduleReference = entry.Schedule.GetReference()
+				response.Message = fmt.Sprintf("Schedule %s added. Next run at %s.",
+					entry.Schedule.GetReference(), entry.Schedule.NextRun.Format(time.RFC1123))
+			}
+		case core.RemoveSchedule:
+			if _, ok := node.StateMachine.ScheduleMap[entry.Schedule.GetReference()]; !ok {
+				err = fmt.Errorf("Schedule %s does not exist.", entry.Schedule.GetReference())
+			} else {
+				response.Message = fmt.Sprintf("Schedule %s removed.", entry.Schedule.GetReference())
+			}
 		}
 
-		node.addEntryToLog(entry)
-		response.Success = true
-		response.Message = fmt.Sprintf("Job %s submitted.", entry.Job.GetReference())
+		if err != nil {
+			response.Success = false
+			response.Message = err.Error()
+		} else {
+			node.addEntryToLog(entry)
+			response.Success = true
+		}
 
 	} else {
 		logger.Debug("Node is not the leader. Ignore AppendEntry command and redirect to leader",
@@ -174,6 +215,7 @@ func (node *SchedulerNode) handleStatusCommand(request core.RequestCommandRPC) {
 	response := core.ResponseCommandRPC{
 		FromNode:    node.Card,
 		ToNode:      request.FromNode,
+		RequestId:   request.RequestId,
 		Term:        node.CurrentTerm,
 		CommandType: request.CommandType,
 		LeaderId:    node.LeaderId,
@@ -185,7 +227,15 @@ func (node *SchedulerNode) handleStatusCommand(request core.RequestCommandRPC) {
 			zap.Int("Number of jobs", len(node.StateMachine.JobMap)),
 		)
 
-		response.JobMap = node.StateMachine.JobMap
+		if len(request.DetailJobReferences) > 0 {
+			response.JobMap = node.getJobDetails(request.DetailJobReferences)
+		} else {
+			response.JobSummaries, response.JobCount = node.queryJobs(request.JobQuery)
+			response.ScheduleMap = make(map[string]core.Schedule, len(node.StateMachine.ScheduleMap))
+			for reference, schedule := range node.StateMachine.ScheduleMap {
+				response.ScheduleMap[reference] = schedule
+			}
+		}
 		response.Success = true
 
 	} else {
@@ -219,6 +269,10 @@ func (node *SchedulerNode) handleRequestCommandRPC(request core.RequestCommandRP
 		node.handleRecoverCommand()
 	case core.StatusCommand:
 		node.handleStatusCommand(request)
+	case core.WaitCommand:
+		node.handleWaitCommand(request)
+	case core.WatchCommand:
+		node.handleWatchCommand(request)
 	}
 }
 
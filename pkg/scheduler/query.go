@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"sort"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/***********
+ ** Query **
+ ***********/
+
+// queryJobs returns the summaries of the page of the jobs selected by a query, sorted, and the
+// number of selected jobs
+func (node *SchedulerNode) queryJobs(query core.JobQuery) ([]core.JobSummary, uint32) {
+	var jobList []core.Job
+	for _, job := range node.StateMachine.JobMap {
+		if query.Matches(job) {
+			jobList = append(jobList, job)
+		}
+	}
+	sort.Slice(jobList, func(i, j int) bool { return query.Less(jobList[i], jobList[j]) })
+
+	jobCount := uint32(len(jobList))
+	if query.Offset >= jobCount {
+		return nil, jobCount
+	}
+	end := jobCount
+	if limit := query.GetLimit(); end-query.Offset > limit {
+		end = query.Offset + limit
+	}
+	summaryList := make([]core.JobSummary, 0, end-query.Offset)
+	for i := query.Offset; i < end; i++ {
+		summaryList = append(summaryList, jobList[i].GetSummary())
+	}
+	return summaryList, jobCount
+}
+
+// getJobDetails returns the given jobs with all their fields, with the jobs of the job arrays
+func (node *SchedulerNode) getJobDetails(referenceList []string) map[string]core.Job {
+	jobMap := make(map[string]core.Job)
+	for _, reference := range referenceList {
+		job, ok := node.StateMachine.JobMap[reference]
+		if !ok {
+			continue
+		}
+		jobMap[reference] = job
+		for index := job.ArrayStart; job.IsArray() && index < job.ArrayStart+int(job.ArraySize); index++ {
+			childReference := job.GetArrayChildReference(index)
+			jobMap[childReference] = node.StateMachine.JobMap[childReference]
+		}
+	}
+	return jobMap
+}
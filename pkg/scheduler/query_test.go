@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// queryJobs must return the page of the sorted jobs given by the offset and the limit of the query
+func TestQueryJobsPagination(t *testing.T) {
+	node := &SchedulerNode{}
+	node.StateMachine.Init()
+	for term := uint32(1); term <= 2; term++ {
+		for id := uint32(1); id <= 5; id++ {
+			job := core.Job{Id: id, Term: term}
+			node.StateMachine.JobMap[job.GetReference()] = job
+		}
+	}
+
+	tests := []struct {
+		name     string
+		query    core.JobQuery
+		expected []string
+	}{
+		{"first page", core.JobQuery{Limit: 3}, []string{"1-1", "2-1", "3-1"}},
+		{"next page", core.JobQuery{Offset: 3, Limit: 3}, []string{"4-1", "5-1", "1-2"}},
+		{"last page", core.JobQuery{Offset: 9, Limit: 3}, []string{"5-2"}},
+		{"after the last page", core.JobQuery{Offset: 10, Limit: 3}, nil},
+		{"descending", core.JobQuery{Descending: true, Limit: 2}, []string{"5-2", "4-2"}},
+		{"default limit", core.JobQuery{Offset: 8}, []string{"4-2", "5-2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			summaryList, jobCount := node.queryJobs(test.query)
+			if jobCount != 10 {
+				t.Fatalf("%d jobs selected instead of 10", jobCount)
+			}
+			if len(summaryList) != len(test.expected) {
+				t.Fatalf("%d jobs listed instead of %d", len(summaryList), len(test.expected))
+			}
+			for i, summary := range summaryList {
+				if summary.Reference != test.expected[i] {
+					t.Fatalf("job %s listed at position %d instead of job %s", summary.Reference, i, test.expected[i])
+				}
+			}
+		})
+	}
+}
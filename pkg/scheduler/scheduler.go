@@ -37,6 +37,8 @@ type SchedulerNode struct {
 	log map[uint32]core.Entry
 	// Job id counter
 	jobIdCounter uint32
+	// Schedule id counter
+	scheduleIdCounter uint32
 	// Index of highest log entry known to be committed (initialized to 0, increases monotonically)
 	commitIndex uint32
 	// Index of highest log entry known to be replicated on other nodes (initialized to 0, increases monotonically)
@@ -48,6 +50,16 @@ type SchedulerNode struct {
 
 	// StateMachine
 	StateMachine StateMachine
+	// Jobs waiting for their retry time before being sent to a worker (only used by the leader)
+	delayedJobs []core.Job
+	// WaitCommand requests held until their jobs have ended (only used by the leader)
+	waitingRequests []waitingRequest
+	// Subscriptions to the state changes of jobs (only used by the leader)
+	watchers []*jobWatcher
+	// Last events of the jobs changed by the applied entries, replayed to the subscribers which resume
+	// their subscription. It holds all the events of the entries after eventHistoryIndex.
+	eventHistory      []core.JobEvent
+	eventHistoryIndex uint32
 
 	Channel core.ChannelContainer
 
@@ -132,6 +144,10 @@ func (node *SchedulerNode) Run(wg *sync.WaitGroup) {
 		node.printNodeStateInFile()
 		node.updateCommitIndex()
 		node.updateStateMachine()
+		node.answerWaitingRequests()
+		node.updateWatchers()
+		node.dispatchDelayedJobs()
+		node.fireDueSchedules()
 		time.Sleep(core.Config.NodeSpeedList[node.Id])
 	}
 }
@@ -171,6 +187,10 @@ func (node *SchedulerNode) printNodeStateInFile() {
 	fmt.Fprintln(f, "### Log ###")
 	for i := 1; i <= len(node.log); i++ {
 		entry := node.log[uint32(i)]
+		if entry.Type == core.AddSchedule || entry.Type == core.RemoveSchedule {
+			fmt.Fprintf(f, "[%v] Schedule %v | %v\n", i, entry.Schedule.GetReference(), entry.Type.String())
+			continue
+		}
 		fmt.Fprintf(f, "[%v] Job %v | Worker %v | %v\n", i, entry.Job.GetReference(), entry.Job.WorkerId, entry.Job.State.String())
 	}
 	fmt.Fprintln(f, "----------------")
@@ -238,6 +258,21 @@ func (node *SchedulerNode) becomeLeader() {
 		node.nextIndex[nodeId] = uint32(len(node.log)) + 1
 	}
 	node.jobIdCounter = 0
+	node.scheduleIdCounter = 0
+
+	// Take over the retries planned by the previous leader, including the ones which are already due
+	// but have not been sent to their worker: dispatchDelayedJobs sends them at once. The retries of
+	// the entries which are not applied yet are planned when the leader applies them.
+	node.delayedJobs = nil
+	for reference, job := range node.StateMachine.JobMap {
+		if job.State != core.JobWaiting || job.RetryAt.IsZero() {
+			continue
+		}
+		if core.Config.NodeChannelMap[core.WorkerNodeType][job.WorkerId].JobQueue.Contains(reference) {
+			continue
+		}
+		node.delayedJobs = append(node.delayedJobs, job)
+	}
 }
 
 // updateTerm updates the term of the node if the term is higher than the current term
@@ -309,21 +344,204 @@ func (node *SchedulerNode) updateStateMachine() {
 
 	for i := node.lastApplied + 1; i <= node.commitIndex; i++ {
 		entry := node.log[i]
-		node.StateMachine.Apply(entry)
-
-		// Propagate the job to the worker if the job is new
-		if node.State == core.LeaderState && entry.Type == core.OpenJob {
-			node.sendJobToWorker(&entry.Job)
+		readyJobs := node.StateMachine.Apply(entry)
+		node.recordJobEvents(i, entry)
+
+		// Propagate the jobs whose dependencies are satisfied to the workers
+		if node.State == core.LeaderState {
+			for j := range readyJobs {
+				node.sendJobToWorker(&readyJobs[j])
+			}
+			if entry.Type == core.CancelJob {
+				node.stopCancelledJob(entry.Job.GetReference())
+			}
+			node.publishJobEvents(i, entry)
 		}
 	}
 	node.lastApplied = node.commitIndex
 }
 
-// Send a job to the worker
+// Send a job to the worker, or delay it until its retry time
 func (node *SchedulerNode) sendJobToWorker(job *core.Job) {
+	if job.RetryAt.After(time.Now()) {
+		node.delayedJobs = append(node.delayedJobs, *job)
+		return
+	}
 	workerId := job.WorkerId
 	queue := core.Config.NodeChannelMap[core.WorkerNodeType][workerId].JobQueue
-	queue <- *job
+	queue.Push(*job)
+}
+
+// prepareJob checks a new job and sets the fields decided by the leader before appending it to the log
+func (node *SchedulerNode) prepareJob(job *core.Job) error {
+	for _, dependency := range job.Dependencies {
+		if !node.isJobInLog(dependency) {
+			return fmt.Errorf("Job %s does not exist. It can not be used as a dependency.", dependency)
+		}
+	}
+	if job.ArraySize > core.Config.MaxArraySize {
+		return fmt.Errorf("A job array can not contain more than %d jobs.", core.Config.MaxArraySize)
+	}
+	job.WorkerId = int(node.GetWorkerId(job.Priority))
+	job.Id = node.GetJobId()
+	job.Term = node.CurrentTerm
+	job.State = core.JobWaiting
+	job.SubmittedAt = time.Now()
+	return nil
+}
+
+// prepareSchedule checks a new schedule and computes its first run before appending it to the log
+func (node *SchedulerNode) prepareSchedule(schedule *core.Schedule) error {
+	for _, dependency := range schedule.Job.Dependencies {
+		if !node.isJobInLog(dependency) {
+			return fmt.Errorf("Job %s does not exist. It can not be used as a dependency.", dependency)
+		}
+	}
+	if schedule.IsRecurring() {
+		cron, err := core.ParseCronExpression(schedule.Cron)
+		if err != nil {
+			return err
+		}
+		schedule.NextRun = cron.Next(time.Now())
+		if schedule.NextRun.IsZero() {
+			return fmt.Errorf("The cron expression %q never matches.", schedule.Cron)
+		}
+	}
+	node.scheduleIdCounter++
+	schedule.Id = node.scheduleIdCounter
+	schedule.Term = node.CurrentTerm
+	return nil
+}
+
+// fireDueSchedules appends a new job to the log for each schedule whose next run has come
+func (node *SchedulerNode) fireDueSchedules() {
+	if node.State != core.LeaderState || node.IsCrashed {
+		return
+	}
+
+	now := time.Now()
+	for reference, schedule := range node.StateMachine.ScheduleMap {
+		if schedule.NextRun.After(now) || node.isScheduleFiredInLog(reference, schedule.NextRun) {
+			continue
+		}
+
+		job := schedule.Job
+		job.ScheduleReference = reference
+		job.ScheduledAt = schedule.NextRun
+		if err := node.prepareJob(&job); err != nil {
+			logger.Error("Error while creating a job from a schedule",
+				zap.String("Node", node.Card.String()),
+				zap.String("ScheduleRef", reference),
+				zap.Error(err),
+			)
+			continue
+		}
+		logger.Info("Fire schedule",
+			zap.String("Node", node.Card.String()),
+			zap.String("ScheduleRef", reference),
+			zap.String("JobRef", job.GetReference()),
+		)
+		node.addEntryToLog(core.Entry{Type: core.OpenJob, Term: node.CurrentTerm, Job: job})
+	}
+}
+
+// isScheduleFiredInLog checks if a job has already been created for a run of a schedule in the entries not applied yet
+func (node *SchedulerNode) isScheduleFiredInLog(reference string, scheduledAt time.Time) bool {
+	for i := node.lastApplied + 1; i <= uint32(len(node.log)); i++ {
+		job := node.log[i].Job
+		if node.log[i].Type == core.OpenJob && job.ScheduleReference == reference && job.ScheduledAt.Equal(scheduledAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJobInLog checks if a job has been opened in the log, even if the entry is not committed yet.
+// A job created by a job array exists if the array has been opened and contains its index.
+func (node *SchedulerNode) isJobInLog(reference string) bool {
+	job, err := core.ParseJobReference(reference)
+	if err != nil {
+		return false
+	}
+	openedReference := reference
+	if job.IsArrayChild() {
+		openedReference = job.ArrayParent
+	}
+
+	for _, entry := range node.log {
+		if entry.Type != core.OpenJob || entry.Job.GetReference() != openedReference {
+			continue
+		}
+		if !job.IsArrayChild() {
+			return true
+		}
+		return entry.Job.IsArray() &&
+			job.ArrayIndex >= entry.Job.ArrayStart &&
+			job.ArrayIndex < entry.Job.ArrayStart+int(entry.Job.ArraySize)
+	}
+	return false
+}
+
+// dispatchDelayedJobs sends the delayed jobs whose retry time has come to their worker
+func (node *SchedulerNode) dispatchDelayedJobs() {
+	if node.State != core.LeaderState {
+		node.delayedJobs = nil
+		return
+	}
+	if node.IsCrashed || len(node.delayedJobs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	remainingJobs := node.delayedJobs[:0]
+	var dueJobs []core.Job
+	for _, job := range node.delayedJobs {
+		if job.RetryAt.After(now) {
+			remainingJobs = append(remainingJobs, job)
+		} else {
+			dueJobs = append(dueJobs, job)
+		}
+	}
+	node.delayedJobs = remainingJobs
+
+	for i := range dueJobs {
+		// The job may have been cancelled while it was delayed
+		if node.StateMachine.JobMap[dueJobs[i].GetReference()].State != core.JobWaiting {
+			continue
+		}
+		logger.Info("Send delayed job to worker",
+			zap.String("Node", node.Card.String()),
+			zap.String("JobRef", dueJobs[i].GetReference()),
+		)
+		node.sendJobToWorker(&dueJobs[i])
+	}
+}
+
+// stopCancelledJob removes a cancelled job from the queue of its worker, or asks the worker to stop it if it is running
+func (node *SchedulerNode) stopCancelledJob(reference string) {
+	job, ok := node.StateMachine.JobMap[reference]
+	if !ok {
+		return
+	}
+	if job.IsArray() {
+		for index := job.ArrayStart; index < job.ArrayStart+int(job.ArraySize); index++ {
+			node.stopCancelledJob(job.GetArrayChildReference(index))
+		}
+		return
+	}
+	if job.State != core.JobCancelled || job.WorkerId == core.NO_WORKER {
+		return
+	}
+
+	logger.Info("Stop cancelled job",
+		zap.String("Node", node.Card.String()),
+		zap.String("JobRef", reference),
+		zap.Int("WorkerId", job.WorkerId),
+	)
+	container := core.Config.NodeChannelMap[core.WorkerNodeType][job.WorkerId]
+	if !container.JobQueue.Remove(reference) {
+		container.CancelJob <- reference
+	}
 }
 
 // GetJobId generates a new job id and increments the job id counter
@@ -332,12 +550,12 @@ func (node *SchedulerNode) GetJobId() uint32 {
 	return node.jobIdCounter
 }
 
-// GetWorkerId finds the appropriate worker id for the job (the worker with the lowest load)
-func (node *SchedulerNode) GetWorkerId() uint32 {
-	// the number of jobs in the queue for each worker
+// GetWorkerId finds the appropriate worker id for a job with the given priority (the worker with the lowest load)
+func (node *SchedulerNode) GetWorkerId(priority core.JobPriority) uint32 {
+	// the number of jobs in the queue for each worker which will be executed before the job
 	jobCount := make([]uint32, core.Config.WorkerNodeCount)
 	for i, container := range core.Config.NodeChannelMap[core.WorkerNodeType] {
-		jobCount[i] = uint32(len(container.JobQueue))
+		jobCount[i] = uint32(container.JobQueue.CountAhead(priority))
 	}
 	// get the worker id with the lowest number of jobs in the queue
 	return utils.IndexMinUint32(jobCount)
@@ -6,21 +6,220 @@ import (
 )
 
 type StateMachine struct {
-	JobMap map[string]core.Job
+	JobMap      map[string]core.Job
+	ScheduleMap map[string]core.Schedule
+
+	// References of the jobs depending on each job
+	dependents map[string][]string
+
+	// References of the jobs changed by the last applied entry, in the order of their first change
+	changedJobs   []string
+	changedJobSet map[string]bool
 }
 
 // Init initializes the state machine
 func (sm *StateMachine) Init() {
 	sm.JobMap = make(map[string]core.Job)
+	sm.ScheduleMap = make(map[string]core.Schedule)
+	sm.dependents = make(map[string][]string)
 }
 
-// Apply an Entry to the state machine
-func (sm *StateMachine) Apply(entry core.Entry) {
+// Apply an Entry to the state machine and return the jobs which are ready to be executed
+func (sm *StateMachine) Apply(entry core.Entry) []core.Job {
+	sm.changedJobs = nil
+	sm.changedJobSet = make(map[string]bool)
+
+	switch entry.Type {
+	case core.AddSchedule:
+		logger.Info("Applying entry to the StateMachine",
+			zap.String("ScheduleRef", entry.Schedule.GetReference()),
+			zap.String("EntryType", entry.Type.String()),
+		)
+		sm.ScheduleMap[entry.Schedule.GetReference()] = entry.Schedule
+		return nil
+	case core.RemoveSchedule:
+		logger.Info("Applying entry to the StateMachine",
+			zap.String("ScheduleRef", entry.Schedule.GetReference()),
+			zap.String("EntryType", entry.Type.String()),
+		)
+		delete(sm.ScheduleMap, entry.Schedule.GetReference())
+		return nil
+	}
+
 	logger.Info("Applying entry to the StateMachine",
 		zap.String("JobRef", entry.Job.GetReference()),
 		zap.String("EntryType", entry.Type.String()),
 	)
-	sm.JobMap[entry.Job.GetReference()] = entry.Job
+	reference := entry.Job.GetReference()
+
+	switch entry.Type {
+	case core.OpenJob:
+		sm.setJob(reference, entry.Job)
+		if entry.Job.ScheduleReference != "" {
+			sm.advanceSchedule(entry.Job)
+		}
+		if entry.Job.IsArray() {
+			return sm.openArray(entry.Job)
+		}
+		sm.addDependencies(reference, entry.Job.Dependencies)
+		return sm.resolveDependencies(reference)
+	case core.CloseJob:
+		if current, ok := sm.JobMap[reference]; ok && current.State.IsTerminal() {
+			logger.Info("Ignore the result of a job which has been cancelled",
+				zap.String("JobRef", reference),
+			)
+			return nil
+		}
+		job := entry.Job
+		if job.PrepareRetry() {
+			logger.Info("Retry failed job",
+				zap.String("JobRef", reference),
+				zap.Int("Attempt", len(job.Attempts)),
+				zap.Time("RetryAt", job.RetryAt),
+			)
+			sm.setJob(reference, job)
+			return []core.Job{job}
+		}
+		sm.setJob(reference, job)
+		return sm.completeJob(reference)
+	case core.CancelJob:
+		return sm.cancelJob(reference)
+	}
+	return nil
+}
+
+// setJob updates a job and records that it has been changed by the entry being applied
+func (sm *StateMachine) setJob(reference string, job core.Job) {
+	sm.JobMap[reference] = job
+	if !sm.changedJobSet[reference] {
+		sm.changedJobSet[reference] = true
+		sm.changedJobs = append(sm.changedJobs, reference)
+	}
+}
+
+// addDependencies registers a job as a dependent of each of its parents
+func (sm *StateMachine) addDependencies(reference string, dependencies []string) {
+	for _, parent := range dependencies {
+		sm.dependents[parent] = append(sm.dependents[parent], reference)
+	}
+}
+
+// openArray creates the jobs of a job array and returns the ones which are ready to be executed
+func (sm *StateMachine) openArray(array core.Job) []core.Job {
+	var readyJobs []core.Job
+	for index := array.ArrayStart; index < array.ArrayStart+int(array.ArraySize); index++ {
+		child := array.GetArrayChild(index)
+		reference := child.GetReference()
+		sm.setJob(reference, child)
+		sm.addDependencies(reference, child.Dependencies)
+		readyJobs = append(readyJobs, sm.resolveDependencies(reference)...)
+	}
+	return readyJobs
+}
+
+// updateArray counts the jobs of a job array in each state and returns true if the array has just ended
+func (sm *StateMachine) updateArray(reference string) bool {
+	array, ok := sm.JobMap[reference]
+	if !ok || array.State.IsTerminal() {
+		return false
+	}
+
+	stateCount := make(map[core.JobState]uint32)
+	for index := array.ArrayStart; index < array.ArrayStart+int(array.ArraySize); index++ {
+		stateCount[sm.JobMap[array.GetArrayChildReference(index)].State]++
+	}
+	array.ArrayStateCount = stateCount
+
+	if stateCount[core.JobWaiting] == 0 {
+		switch {
+		case stateCount[core.JobFailed] > 0:
+			array.State = core.JobFailed
+		case stateCount[core.JobCancelled] > 0:
+			array.State = core.JobCancelled
+		case stateCount[core.JobSkipped] > 0:
+			array.State = core.JobSkipped
+		default:
+			array.State = core.JobSucceeded
+		}
+	}
+	sm.setJob(reference, array)
+	return array.State.IsTerminal()
+}
+
+// completeJob propagates the end of a job to its job array and to the jobs depending on it.
+// It returns the jobs which are ready to be executed.
+func (sm *StateMachine) completeJob(reference string) []core.Job {
+	job := sm.JobMap[reference]
+	var readyJobs []core.Job
+	if job.IsArrayChild() && sm.updateArray(job.ArrayParent) {
+		readyJobs = append(readyJobs, sm.completeJob(job.ArrayParent)...)
+	}
+	for _, dependent := range sm.dependents[reference] {
+		readyJobs = append(readyJobs, sm.resolveDependencies(dependent)...)
+	}
+	return readyJobs
+}
+
+// cancelJob cancels a job, or all the jobs of a job array, if it has not ended yet
+func (sm *StateMachine) cancelJob(reference string) []core.Job {
+	job, ok := sm.JobMap[reference]
+	if !ok || job.State.IsTerminal() {
+		return nil
+	}
+
+	if job.IsArray() {
+		var readyJobs []core.Job
+		for index := job.ArrayStart; index < job.ArrayStart+int(job.ArraySize); index++ {
+			readyJobs = append(readyJobs, sm.cancelJob(job.GetArrayChildReference(index))...)
+		}
+		return readyJobs
+	}
+
+	job.State = core.JobCancelled
+	sm.setJob(reference, job)
+	return sm.completeJob(reference)
+}
+
+// resolveDependencies checks the parents of a waiting job. The job is returned if all its parents
+// have succeeded. It is skipped, as well as its own dependents, if one of its parents has not succeeded.
+func (sm *StateMachine) resolveDependencies(reference string) []core.Job {
+	job := sm.JobMap[reference]
+	if job.State != core.JobWaiting {
+		return nil
+	}
+
+	for _, parent := range job.Dependencies {
+		parentJob, ok := sm.JobMap[parent]
+		if ok && parentJob.State == core.JobSucceeded {
+			continue
+		}
+		if ok && !parentJob.State.IsTerminal() {
+			return nil
+		}
+
+		logger.Info("Skip job because one of its dependencies has not succeeded",
+			zap.String("JobRef", reference),
+			zap.String("Dependency", parent),
+		)
+		job.State = core.JobSkipped
+		sm.setJob(reference, job)
+		return sm.completeJob(reference)
+	}
+	return []core.Job{job}
+}
+
+// advanceSchedule plans the next run of the schedule which created a job
+func (sm *StateMachine) advanceSchedule(job core.Job) {
+	schedule, ok := sm.ScheduleMap[job.ScheduleReference]
+	if !ok || !schedule.NextRun.Equal(job.ScheduledAt) {
+		return
+	}
+	// The next run is computed from the submission to skip the runs missed while there was no leader
+	if schedule.Advance(job.SubmittedAt) {
+		sm.ScheduleMap[job.ScheduleReference] = schedule
+	} else {
+		delete(sm.ScheduleMap, job.ScheduleReference)
+	}
 }
 
 // Load a snapshot in the state machine
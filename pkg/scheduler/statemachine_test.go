@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"testing"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// newTestStateMachine returns an initialized state machine
+func newTestStateMachine() *StateMachine {
+	sm := &StateMachine{}
+	sm.Init()
+	return sm
+}
+
+// openTestJob applies the entry opening a job with the given id and dependencies
+func openTestJob(sm *StateMachine, id uint32, dependencies ...string) []core.Job {
+	job := core.Job{Id: id, Term: 1, State: core.JobWaiting, Dependencies: dependencies}
+	return sm.Apply(core.Entry{Type: core.OpenJob, Term: 1, Job: job})
+}
+
+// closeTestJob applies the entry closing a job in the given state
+func closeTestJob(sm *StateMachine, reference string, state core.JobState) []core.Job {
+	job := sm.JobMap[reference]
+	job.State = state
+	return sm.Apply(core.Entry{Type: core.CloseJob, Term: 1, Job: job})
+}
+
+// getReferenceList returns the references of the given jobs
+func getReferenceList(jobList []core.Job) []string {
+	var referenceList []string
+	for _, job := range jobList {
+		referenceList = append(referenceList, job.GetReference())
+	}
+	return referenceList
+}
+
+// checkReferenceList fails the test if the jobs do not have the expected references
+func checkReferenceList(t *testing.T, jobList []core.Job, expected ...string) {
+	t.Helper()
+	referenceList := getReferenceList(jobList)
+	if len(referenceList) != len(expected) {
+		t.Fatalf("jobs %v ready instead of %v", referenceList, expected)
+	}
+	for i := range expected {
+		if referenceList[i] != expected[i] {
+			t.Fatalf("jobs %v ready instead of %v", referenceList, expected)
+		}
+	}
+}
+
+// checkJobState fails the test if a job is not in the expected state
+func checkJobState(t *testing.T, sm *StateMachine, reference string, expected core.JobState) {
+	t.Helper()
+	if state := sm.JobMap[reference].State; state != expected {
+		t.Fatalf("the job %s is %s instead of %s", reference, state, expected)
+	}
+}
+
+// A job must be ready once all its dependencies have succeeded, and be skipped with its own
+// dependents as soon as one of them has not succeeded
+func TestStateMachineDependencies(t *testing.T) {
+	tests := []struct {
+		name        string
+		parentState core.JobState
+		ready       []string
+		childState  core.JobState
+	}{
+		{"succeeded", core.JobSucceeded, []string{"3-1"}, core.JobWaiting},
+		{"failed", core.JobFailed, nil, core.JobSkipped},
+		{"cancelled", core.JobCancelled, nil, core.JobSkipped},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sm := newTestStateMachine()
+			checkReferenceList(t, openTestJob(sm, 1), "1-1")
+			checkReferenceList(t, openTestJob(sm, 2), "2-1")
+			checkReferenceList(t, openTestJob(sm, 3, "1-1", "2-1"))
+			checkReferenceList(t, openTestJob(sm, 4, "3-1"))
+
+			checkReferenceList(t, closeTestJob(sm, "1-1", core.JobSucceeded))
+			checkReferenceList(t, closeTestJob(sm, "2-1", test.parentState), test.ready...)
+			checkJobState(t, sm, "3-1", test.childState)
+			checkJobState(t, sm, "4-1", test.childState)
+		})
+	}
+}
+
+// A job depending on a job which does not exist or has already ended must be resolved when it is opened
+func TestStateMachineDependenciesOnOpen(t *testing.T) {
+	sm := newTestStateMachine()
+	openTestJob(sm, 1)
+	closeTestJob(sm, "1-1", core.JobSucceeded)
+	checkReferenceList(t, openTestJob(sm, 2, "1-1"), "2-1")
+
+	checkReferenceList(t, openTestJob(sm, 3, "9-1"))
+	checkJobState(t, sm, "3-1", core.JobSkipped)
+}
+
+// A cancelled job must skip its dependents and ignore its result when it is closed afterwards
+func TestStateMachineCancelJob(t *testing.T) {
+	sm := newTestStateMachine()
+	openTestJob(sm, 1)
+	openTestJob(sm, 2, "1-1")
+
+	job := sm.JobMap["1-1"]
+	checkReferenceList(t, sm.Apply(core.Entry{Type: core.CancelJob, Term: 1, Job: job}))
+	checkJobState(t, sm, "1-1", core.JobCancelled)
+	checkJobState(t, sm, "2-1", core.JobSkipped)
+
+	checkReferenceList(t, closeTestJob(sm, "1-1", core.JobSucceeded))
+	checkJobState(t, sm, "1-1", core.JobCancelled)
+}
+
+// A failed job with a retry policy must be ready again instead of ending
+func TestStateMachineRetry(t *testing.T) {
+	sm := newTestStateMachine()
+	job := core.Job{Id: 1, Term: 1, State: core.JobWaiting, RetryPolicy: core.RetryPolicy{MaxAttempts: 2}}
+	sm.Apply(core.Entry{Type: core.OpenJob, Term: 1, Job: job})
+	openTestJob(sm, 2, "1-1")
+
+	job.State = core.JobFailed
+	job.Attempts = []core.JobAttempt{{Number: 1, ExitCode: 1}}
+	checkReferenceList(t, sm.Apply(core.Entry{Type: core.CloseJob, Term: 1, Job: job}), "1-1")
+	checkJobState(t, sm, "1-1", core.JobWaiting)
+	checkJobState(t, sm, "2-1", core.JobWaiting)
+
+	job.Attempts = append(job.Attempts, core.JobAttempt{Number: 2, ExitCode: 1})
+	checkReferenceList(t, sm.Apply(core.Entry{Type: core.CloseJob, Term: 1, Job: job}))
+	checkJobState(t, sm, "1-1", core.JobFailed)
+	checkJobState(t, sm, "2-1", core.JobSkipped)
+}
+
+// A job array must end once all its jobs have ended, in the state of the worst of them
+func TestStateMachineArray(t *testing.T) {
+	tests := []struct {
+		name       string
+		stateList  []core.JobState
+		arrayState core.JobState
+	}{
+		{"succeeded", []core.JobState{core.JobSucceeded, core.JobSucceeded, core.JobSucceeded}, core.JobSucceeded},
+		{"failed", []core.JobState{core.JobSucceeded, core.JobFailed, core.JobCancelled}, core.JobFailed},
+		{"cancelled", []core.JobState{core.JobCancelled, core.JobSucceeded, core.JobSucceeded}, core.JobCancelled},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sm := newTestStateMachine()
+			array := core.Job{Id: 1, Term: 1, State: core.JobWaiting, ArraySize: 3, ArrayStart: 1}
+			checkReferenceList(t, sm.Apply(core.Entry{Type: core.OpenJob, Term: 1, Job: array}), "1-1_1", "1-1_2", "1-1_3")
+			openTestJob(sm, 2, "1-1")
+
+			for i, state := range test.stateList {
+				checkJobState(t, sm, "1-1", core.JobWaiting)
+				ready := closeTestJob(sm, array.GetArrayChildReference(i+1), state)
+				if i < len(test.stateList)-1 {
+					checkReferenceList(t, ready)
+				} else if test.arrayState == core.JobSucceeded {
+					checkReferenceList(t, ready, "2-1")
+				}
+			}
+			checkJobState(t, sm, "1-1", test.arrayState)
+			if count := sm.JobMap["1-1"].ArrayStateCount[core.JobWaiting]; count != 0 {
+				t.Fatalf("%d jobs of the array are still waiting", count)
+			}
+		})
+	}
+}
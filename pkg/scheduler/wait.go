@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+/**********
+ ** Wait **
+ **********/
+
+// waitingRequest is a WaitCommand held by the leader until the awaited jobs have ended
+type waitingRequest struct {
+	request  core.RequestCommandRPC
+	deadline time.Time
+}
+
+// handleWaitCommand handles the WaitCommand sent to the leader to wait until jobs have ended. The
+// request is answered by answerWaitingRequests once the jobs have ended or its deadline has passed,
+// so that the client does not poll the status of the jobs.
+func (node *SchedulerNode) handleWaitCommand(request core.RequestCommandRPC) {
+	if node.IsCrashed {
+		logger.Debug("Node is crashed. Ignore Wait command",
+			zap.String("Node", node.Card.String()),
+		)
+		return
+	}
+
+	if node.State != core.LeaderState {
+		logger.Debug("Node is not the leader. Ignore Wait command and redirect to leader",
+			zap.String("Node", node.Card.String()),
+			zap.Int("Presumed leader id", node.LeaderId),
+		)
+		node.answerWaitCommand(request, nil, false, nil)
+		return
+	}
+
+	waitTimeout := core.Config.WaitPollTimeout
+	if request.WaitTimeout > 0 && request.WaitTimeout < waitTimeout {
+		waitTimeout = request.WaitTimeout
+	}
+	node.waitingRequests = append(node.waitingRequests, waitingRequest{
+		request:  request,
+		deadline: time.Now().Add(waitTimeout),
+	})
+}
+
+// answerWaitingRequests answers the waiting requests whose jobs have ended or whose deadline has
+// passed. The requests are answered at once if the node is not the leader anymore, so that the
+// client waits on the new leader, and dropped if the node has crashed.
+func (node *SchedulerNode) answerWaitingRequests() {
+	if len(node.waitingRequests) == 0 {
+		return
+	}
+	if node.IsCrashed {
+		node.waitingRequests = nil
+		return
+	}
+
+	var remainingRequests []waitingRequest
+	for _, waiting := range node.waitingRequests {
+		if node.State != core.LeaderState {
+			node.answerWaitCommand(waiting.request, nil, false, nil)
+			continue
+		}
+		jobMap, jobsEnded, err := node.getAwaitedJobs(waiting.request.JobReferences)
+		if err != nil || jobsEnded || time.Now().After(waiting.deadline) {
+			node.answerWaitCommand(waiting.request, jobMap, jobsEnded, err)
+			continue
+		}
+		remainingRequests = append(remainingRequests, waiting)
+	}
+	node.waitingRequests = remainingRequests
+}
+
+// getAwaitedJobs returns the awaited jobs and if they have all ended. A job opened in the log but
+// not applied to the state machine yet has not ended. The jobs are copied in a new map since the
+// client reads it while the state machine changes.
+func (node *SchedulerNode) getAwaitedJobs(referenceList []string) (map[string]core.Job, bool, error) {
+	if len(referenceList) == 0 {
+		jobMap := make(map[string]core.Job, len(node.StateMachine.JobMap))
+		jobsEnded := true
+		for reference, job := range node.StateMachine.JobMap {
+			jobMap[reference] = job
+			if !job.State.IsTerminal() {
+				jobsEnded = false
+			}
+		}
+		for index := node.lastApplied + 1; jobsEnded && index <= uint32(len(node.log)); index++ {
+			if node.log[index].Type == core.OpenJob {
+				jobsEnded = false
+			}
+		}
+		return jobMap, jobsEnded, nil
+	}
+
+	jobMap := make(map[string]core.Job)
+	jobsEnded := true
+	for _, reference := range referenceList {
+		job, ok := node.StateMachine.JobMap[reference]
+		if !ok && !node.isJobInLog(reference) {
+			return nil, false, fmt.Errorf("Job %s does not exist.", reference)
+		}
+		if ok {
+			jobMap[reference] = job
+		}
+		if !ok || !job.State.IsTerminal() {
+			jobsEnded = false
+		}
+	}
+	return jobMap, jobsEnded, nil
+}
+
+// answerWaitCommand sends the answer to a WaitCommand
+func (node *SchedulerNode) answerWaitCommand(request core.RequestCommandRPC, jobMap map[string]core.Job, jobsEnded bool, err error) {
+	response := core.ResponseCommandRPC{
+		FromNode:    node.Card,
+		ToNode:      request.FromNode,
+		RequestId:   request.RequestId,
+		Term:        node.CurrentTerm,
+		CommandType: request.CommandType,
+		LeaderId:    node.LeaderId,
+		Success:     node.State == core.LeaderState && err == nil,
+		JobMap:      jobMap,
+		JobsEnded:   jobsEnded,
+	}
+	if err != nil {
+		response.Message = err.Error()
+	}
+	core.Config.NodeChannelMap[request.FromNode.Type][request.FromNode.Id].ResponseCommand <- response
+}
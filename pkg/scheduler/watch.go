@@ -0,0 +1,264 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+/*****************
+ ** Job Watcher **
+ *****************/
+
+// jobWatcher sends the events of a subscription to its subscriber. The events are queued by the
+// leader and sent by the watcher, so that a slow subscriber never blocks the leader.
+type jobWatcher struct {
+	fromNode     core.NodeCard
+	subscription core.JobSubscription
+	mutex        sync.Mutex
+	queue        []core.JobEvent
+	// Signals that events have been queued or that the watcher has been closed
+	wakeup chan struct{}
+	closed bool
+}
+
+// newJobWatcher creates the watcher of a subscription and starts sending its events
+func newJobWatcher(fromNode core.NodeCard, subscription core.JobSubscription) *jobWatcher {
+	watcher := &jobWatcher{
+		fromNode:     fromNode,
+		subscription: subscription,
+		wakeup:       make(chan struct{}, 1),
+	}
+	go watcher.run()
+	return watcher
+}
+
+// push queues the events of an entry. The subscription is ended if the subscriber has not read
+// Config.MaxWatchQueueSize events yet: it returns false in this case.
+func (watcher *jobWatcher) push(eventList []core.JobEvent) bool {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	if watcher.closed {
+		return false
+	}
+	if len(watcher.queue) >= int(core.Config.MaxWatchQueueSize) {
+		watcher.closeLocked()
+		return false
+	}
+	watcher.queue = append(watcher.queue, eventList...)
+	watcher.signal()
+	return true
+}
+
+// close ends the subscription once the queued events have been sent
+func (watcher *jobWatcher) close() {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	watcher.closeLocked()
+}
+
+// closeLocked ends the subscription, the mutex being held
+func (watcher *jobWatcher) closeLocked() {
+	watcher.closed = true
+	watcher.signal()
+}
+
+// isClosed checks if the subscription has ended
+func (watcher *jobWatcher) isClosed() bool {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+	return watcher.closed
+}
+
+// signal wakes the watcher up without blocking
+func (watcher *jobWatcher) signal() {
+	select {
+	case watcher.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the queued events to the subscriber until the subscription ends or the subscriber stops
+// watching. The events channel is closed after all the events of an entry.
+func (watcher *jobWatcher) run() {
+	defer close(watcher.subscription.Events)
+	for {
+		watcher.mutex.Lock()
+		eventList := watcher.queue
+		watcher.queue = nil
+		closed := watcher.closed
+		watcher.mutex.Unlock()
+
+		for _, event := range eventList {
+			select {
+			case watcher.subscription.Events <- event:
+			case <-watcher.subscription.Done:
+				watcher.close()
+				return
+			}
+		}
+		if closed {
+			return
+		}
+
+		select {
+		case <-watcher.wakeup:
+		case <-watcher.subscription.Done:
+			watcher.close()
+			return
+		}
+	}
+}
+
+/***********
+ ** Watch **
+ ***********/
+
+// handleWatchCommand handles the WatchCommand sent to the leader to subscribe to the state changes
+// of jobs. The events of the entries already applied after the given index are replayed first, if
+// they are still in the event history of the node.
+func (node *SchedulerNode) handleWatchCommand(request core.RequestCommandRPC) {
+	if node.IsCrashed {
+		logger.Debug("Node is crashed. Ignore Watch command",
+			zap.String("Node", node.Card.String()),
+		)
+		return
+	}
+
+	channel := core.Config.NodeChannelMap[request.FromNode.Type][request.FromNode.Id].ResponseCommand
+	response := core.ResponseCommandRPC{
+		FromNode:    node.Card,
+		ToNode:      request.FromNode,
+		RequestId:   request.RequestId,
+		Term:        node.CurrentTerm,
+		CommandType: request.CommandType,
+		LeaderId:    node.LeaderId,
+	}
+
+	if node.State != core.LeaderState {
+		logger.Debug("Node is not the leader. Ignore Watch command and redirect to leader",
+			zap.String("Node", node.Card.String()),
+			zap.Int("Presumed leader id", node.LeaderId),
+		)
+		response.Success = false
+		channel <- response
+		return
+	}
+
+	subscription := request.Subscription
+	if subscription.JobReference != "" && !node.isJobInLog(subscription.JobReference) {
+		response.Success = false
+		response.Message = fmt.Sprintf("Job %s does not exist.", subscription.JobReference)
+		channel <- response
+		return
+	}
+
+	logger.Info("Job events watched",
+		zap.String("Node", node.Card.String()),
+		zap.String("Subscriber", request.FromNode.String()),
+		zap.String("JobRef", subscription.JobReference),
+		zap.Bool("Replay", subscription.Replay),
+		zap.Uint32("FromIndex", subscription.FromIndex),
+	)
+	replay := subscription.Replay && subscription.FromIndex < node.lastApplied
+	if replay && subscription.FromIndex < node.eventHistoryIndex {
+		response.Success = false
+		response.Message = fmt.Sprintf("The events of the entries up to index %d are not kept anymore.", node.eventHistoryIndex)
+		channel <- response
+		return
+	}
+
+	watcher := newJobWatcher(request.FromNode, subscription)
+	response.Success = true
+	response.AppliedIndex = node.lastApplied
+	if job, ok := node.StateMachine.JobMap[subscription.JobReference]; ok {
+		response.JobMap = map[string]core.Job{subscription.JobReference: job}
+	}
+	channel <- response
+
+	if replay && !node.replayJobEvents(watcher) {
+		return
+	}
+	node.watchers = append(node.watchers, watcher)
+}
+
+// replayJobEvents sends the events of the entries already applied after the index given by the
+// subscription, from the event history of the node. It returns false if the subscription has ended.
+func (node *SchedulerNode) replayJobEvents(watcher *jobWatcher) bool {
+	var eventList []core.JobEvent
+	for _, event := range node.eventHistory {
+		if event.Index > watcher.subscription.FromIndex && watcher.subscription.Matches(event.Job) {
+			eventList = append(eventList, event)
+		}
+	}
+	return len(eventList) == 0 || watcher.push(eventList)
+}
+
+// recordJobEvents adds the events of an entry which has just been applied to the event history. The
+// oldest entries are removed beyond Config.MaxWatchHistorySize events.
+func (node *SchedulerNode) recordJobEvents(index uint32, entry core.Entry) {
+	for _, reference := range node.StateMachine.changedJobs {
+		node.eventHistory = append(node.eventHistory, core.JobEvent{Index: index, EntryType: entry.Type, Job: node.StateMachine.JobMap[reference]})
+	}
+	if len(node.eventHistory) <= int(core.Config.MaxWatchHistorySize) {
+		return
+	}
+
+	// Remove whole entries, so that the history holds all the events of the entries after its index
+	removedCount := len(node.eventHistory) - int(core.Config.MaxWatchHistorySize)
+	node.eventHistoryIndex = node.eventHistory[removedCount-1].Index
+	for removedCount < len(node.eventHistory) && node.eventHistory[removedCount].Index == node.eventHistoryIndex {
+		removedCount++
+	}
+	node.eventHistory = node.eventHistory[removedCount:]
+}
+
+// publishJobEvents sends the events of an entry which has just been applied to the subscribers
+func (node *SchedulerNode) publishJobEvents(index uint32, entry core.Entry) {
+	for _, watcher := range node.watchers {
+		if eventList := getJobEvents(&node.StateMachine, index, entry, watcher.subscription); len(eventList) > 0 && !watcher.push(eventList) {
+			logger.Warn("Subscriber is too slow. End its subscription",
+				zap.String("Node", node.Card.String()),
+				zap.String("Subscriber", watcher.fromNode.String()),
+			)
+		}
+	}
+}
+
+// updateWatchers removes the subscriptions which have ended. All the subscriptions are ended if the
+// node is not the leader anymore, so that the subscribers resume them on the new leader.
+func (node *SchedulerNode) updateWatchers() {
+	if len(node.watchers) == 0 {
+		return
+	}
+	if node.IsCrashed || node.State != core.LeaderState {
+		for _, watcher := range node.watchers {
+			watcher.close()
+		}
+		node.watchers = nil
+		return
+	}
+
+	var remainingWatchers []*jobWatcher
+	for _, watcher := range node.watchers {
+		if !watcher.isClosed() {
+			remainingWatchers = append(remainingWatchers, watcher)
+		}
+	}
+	node.watchers = remainingWatchers
+}
+
+// getJobEvents returns the events of the jobs watched by a subscription which have been changed by
+// the entry just applied to a state machine
+func getJobEvents(stateMachine *StateMachine, index uint32, entry core.Entry, subscription core.JobSubscription) []core.JobEvent {
+	var eventList []core.JobEvent
+	for _, reference := range stateMachine.changedJobs {
+		job := stateMachine.JobMap[reference]
+		if subscription.Matches(job) {
+			eventList = append(eventList, core.JobEvent{Index: index, EntryType: entry.Type, Job: job})
+		}
+	}
+	return eventList
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/***************
+ ** Artifacts **
+ ***************/
+
+// collectArtifacts archives the files of the workspace matching the artifact patterns of a job after
+// its execution. A pattern matching a directory collects all its files. The symbolic links are not
+// followed, so that a job can not collect files outside of its workspace.
+func collectArtifacts(job *core.Job, workspace string) error {
+	if len(job.ArtifactPatterns) == 0 {
+		return nil
+	}
+	pathMap := make(map[string]bool)
+	for _, pattern := range job.ArtifactPatterns {
+		if err := core.CheckArtifactPattern(pattern); err != nil {
+			return err
+		}
+		matchList, err := filepath.Glob(filepath.Join(workspace, pattern))
+		if err != nil {
+			return err
+		}
+		for _, match := range matchList {
+			pathMap[match] = true
+		}
+	}
+	pathList := make([]string, 0, len(pathMap))
+	for path := range pathMap {
+		pathList = append(pathList, path)
+	}
+	sort.Strings(pathList)
+
+	archive, err := core.CreateArchive(workspace, pathList)
+	if err != nil {
+		return fmt.Errorf("Error while collecting the artifacts of the job: %v", err)
+	}
+	if len(archive) > int(core.Config.MaxArtifactSize) {
+		return fmt.Errorf("The artifacts of the job can not be larger than %d bytes", core.Config.MaxArtifactSize)
+	}
+	if job.ArtifactList, err = core.ListArchive(archive); err != nil {
+		return err
+	}
+	if len(job.ArtifactList) > 0 {
+		job.Artifacts = archive
+	}
+	return nil
+}
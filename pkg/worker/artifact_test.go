@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// writeTestFile writes a file and its parent directories
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A job must not collect the files of a directory outside of its workspace through a symbolic link
+func TestCollectArtifactsRejectsSymlinkedDirectory(t *testing.T) {
+	workspace := t.TempDir()
+	secretDirectory := t.TempDir()
+	writeTestFile(t, filepath.Join(secretDirectory, "key"), "secret")
+	writeTestFile(t, filepath.Join(workspace, "results", "out.csv"), "1,2")
+	if err := os.Symlink(secretDirectory, filepath.Join(workspace, "d")); err != nil {
+		t.Skip("symbolic links are not supported: ", err)
+	}
+
+	job := core.Job{ArtifactPatterns: []string{"d/*"}}
+	if err := collectArtifacts(&job, workspace); err == nil {
+		t.Fatalf("the files of a symlinked directory have been collected: %v", job.ArtifactList)
+	}
+
+	job = core.Job{ArtifactPatterns: []string{"d", "results"}}
+	if err := collectArtifacts(&job, workspace); err != nil {
+		t.Fatal(err)
+	}
+	if len(job.ArtifactList) != 1 || job.ArtifactList[0] != "results/out.csv" {
+		t.Fatalf("unexpected artifacts: %v", job.ArtifactList)
+	}
+}
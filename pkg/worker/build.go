@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+/***********
+ ** Build **
+ ***********/
+
+// getCompileCommand returns the command compiling a job in its workspace. The input of a single-file
+// job is given to the compiler on its standard input. A multi-file job is built by its build command,
+// or by make if there is a Makefile, or by compiling all its source files otherwise.
+func getCompileCommand(ctx context.Context, job *core.Job, binaryName string, workspace string) (*exec.Cmd, error) {
+	if job.Archive == nil {
+		compileArgs := append([]string{"-o", binaryName, "-x", job.GetLanguage(), "-"}, job.CompilerFlags...)
+		compileCommand := exec.CommandContext(ctx, job.GetCompiler(), compileArgs...)
+		compileCommand.Dir = workspace
+		compileCommand.Stdin = strings.NewReader(job.Input)
+		return compileCommand, nil
+	}
+
+	var compileCommand *exec.Cmd
+	switch {
+	case job.BuildCommand != "":
+		compileCommand = exec.CommandContext(ctx, "/bin/sh", "-c", job.BuildCommand)
+	case hasMakefile(workspace):
+		compileCommand = exec.CommandContext(ctx, "make")
+	default:
+		sourceList, err := findSourceFiles(workspace, job.GetLanguage())
+		if err != nil {
+			return nil, err
+		}
+		if len(sourceList) == 0 {
+			return nil, fmt.Errorf("No %s source file in the files of the job", job.GetLanguage())
+		}
+		compileArgs := append([]string{"-o", job.GetExecutable(), "-x", job.GetLanguage()}, sourceList...)
+		compileCommand = exec.CommandContext(ctx, job.GetCompiler(), append(compileArgs, job.CompilerFlags...)...)
+	}
+	compileCommand.Dir = workspace
+	// The compiler and its flags are given to make and to the build command as the usual variables
+	compilerVariable, flagsVariable := "CXX", "CXXFLAGS"
+	if job.GetLanguage() == "c" {
+		compilerVariable, flagsVariable = "CC", "CFLAGS"
+	}
+	compileCommand.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", compilerVariable, job.GetCompiler()),
+		fmt.Sprintf("%s=%s", flagsVariable, strings.Join(job.CompilerFlags, " ")),
+	)
+	return compileCommand, nil
+}
+
+// hasMakefile checks if a workspace contains a Makefile
+func hasMakefile(workspace string) bool {
+	for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+		if _, err := os.Stat(filepath.Join(workspace, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// findSourceFiles returns the paths, relative to the workspace, of the source files of a language
+func findSourceFiles(workspace string, language string) ([]string, error) {
+	var sourceList []string
+	err := filepath.Walk(workspace, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		for _, extension := range core.SourceExtensionMap[language] {
+			if strings.ToLower(filepath.Ext(path)) == extension {
+				relativePath, err := filepath.Rel(workspace, path)
+				if err != nil {
+					return err
+				}
+				sourceList = append(sourceList, relativePath)
+				break
+			}
+		}
+		return nil
+	})
+	return sourceList, err
+}
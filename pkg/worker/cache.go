@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+/*******************
+ ** Compile Cache **
+ *******************/
+
+// compileCacheEntry is a binary stored in the compile cache
+type compileCacheEntry struct {
+	size     int64
+	lastUsed time.Time
+}
+
+// compileCache stores the binaries built by a worker, identified by the hash of everything used to
+// build them (see getCompileCacheKey). The least recently used binaries are removed when the cache
+// exceeds its maximum size.
+type compileCache struct {
+	mutex     sync.Mutex
+	directory string
+	maxSize   int64
+	size      int64
+	entryMap  map[string]*compileCacheEntry
+}
+
+// newCompileCache creates the compile cache of a worker from the binaries already in its directory.
+// It returns nil if the cache is disabled or can not be created.
+func newCompileCache(workerId uint32) *compileCache {
+	if core.Config.CompileCacheSize == 0 {
+		return nil
+	}
+	directory := filepath.Join(core.Config.CompileCacheDirectory, fmt.Sprintf("worker-%d", workerId))
+	if err := os.MkdirAll(directory, os.ModePerm); err != nil {
+		logger.Error("Error while creating compile cache", zap.String("Directory", directory), zap.Error(err))
+		return nil
+	}
+
+	cache := &compileCache{
+		directory: directory,
+		maxSize:   int64(core.Config.CompileCacheSize),
+		entryMap:  make(map[string]*compileCacheEntry),
+	}
+	fileList, err := ioutil.ReadDir(directory)
+	if err != nil {
+		logger.Error("Error while loading compile cache", zap.String("Directory", directory), zap.Error(err))
+		return nil
+	}
+	for _, file := range fileList {
+		if file.Mode().IsRegular() {
+			cache.entryMap[file.Name()] = &compileCacheEntry{size: file.Size(), lastUsed: file.ModTime()}
+			cache.size += file.Size()
+		}
+	}
+	cache.evict()
+	return cache
+}
+
+// getCompileCacheKey returns the hash of everything used to build the binary of a job
+func getCompileCacheKey(job *core.Job) string {
+	hash := sha256.New()
+	for _, field := range []string{
+		job.GetLanguage(),
+		job.GetCompiler(),
+		strings.Join(job.CompilerFlags, "\x00"),
+		job.BuildCommand,
+		job.GetExecutable(),
+		job.Input,
+	} {
+		hash.Write([]byte(field))
+		hash.Write([]byte{0})
+	}
+	hash.Write(job.Archive)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Get copies the binary of a key to a path. It returns false if the key is not in the cache.
+func (cache *compileCache) Get(key string, path string) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	entry, ok := cache.entryMap[key]
+	if !ok {
+		return false
+	}
+	if err := copyFile(filepath.Join(cache.directory, key), path); err != nil {
+		logger.Error("Error while reading compile cache", zap.String("Key", key), zap.Error(err))
+		return false
+	}
+	entry.lastUsed = time.Now()
+	os.Chtimes(filepath.Join(cache.directory, key), entry.lastUsed, entry.lastUsed)
+	return true
+}
+
+// Put stores the binary at a path in the cache
+func (cache *compileCache) Put(key string, path string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if _, ok := cache.entryMap[key]; ok {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > cache.maxSize {
+		return
+	}
+	if err := copyFile(path, filepath.Join(cache.directory, key)); err != nil {
+		logger.Error("Error while writing compile cache", zap.String("Key", key), zap.Error(err))
+		return
+	}
+	cache.entryMap[key] = &compileCacheEntry{size: info.Size(), lastUsed: time.Now()}
+	cache.size += info.Size()
+	cache.evict()
+}
+
+// evict removes the least recently used binaries until the cache does not exceed its maximum size
+func (cache *compileCache) evict() {
+	if cache.size <= cache.maxSize {
+		return
+	}
+	keyList := make([]string, 0, len(cache.entryMap))
+	for key := range cache.entryMap {
+		keyList = append(keyList, key)
+	}
+	sort.Slice(keyList, func(i, j int) bool {
+		return cache.entryMap[keyList[i]].lastUsed.Before(cache.entryMap[keyList[j]].lastUsed)
+	})
+	for _, key := range keyList {
+		if cache.size <= cache.maxSize {
+			return
+		}
+		os.Remove(filepath.Join(cache.directory, key))
+		cache.size -= cache.entryMap[key].size
+		delete(cache.entryMap, key)
+	}
+}
+
+// copyFile copies an executable file. The copy is written to a temporary file first so that an
+// incomplete copy is never used.
+func copyFile(sourcePath string, destinationPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	temporaryPath := destinationPath + ".tmp"
+	destination, err := os.OpenFile(temporaryPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(destination, source)
+	if closeErr := destination.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+	return os.Rename(temporaryPath, destinationPath)
+}
@@ -0,0 +1,262 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+// Controllers enabled for the cgroups of the jobs, to enforce their limits
+var cgroupControllerList = []string{"cpu", "memory"}
+
+// Period of the CPU quota of a job in microseconds
+const cpuQuotaPeriod = 100000
+
+// Number of times the removal of a cgroup is tried while its processes exit
+const cgroupRemoveRetryCount = 50
+
+/************
+ ** Cgroup **
+ ************/
+
+// jobCgroup is the cgroup v2 in which an attempt of a job is executed
+type jobCgroup struct {
+	path string
+	// Controllers available in the cgroup
+	controllerMap map[string]bool
+}
+
+// initCgroupDirectory creates the cgroup of the worker, which contains the cgroups of its jobs.
+// The jobs are executed without cgroups if cgroup v2 is not available.
+func (node *WorkerNode) initCgroupDirectory() {
+	root := core.Config.CgroupDirectory
+	if root == "" {
+		return
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "cgroup.controllers")); err != nil {
+		logger.Warn("cgroup v2 is not available, the jobs are executed without cgroups",
+			zap.String("Node", node.Card.String()),
+			zap.String("Directory", root),
+		)
+		return
+	}
+	directory := filepath.Join(root, fmt.Sprintf("worker-%d", node.Id))
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		logger.Warn("Error while creating the cgroup of the worker, the jobs are executed without cgroups",
+			zap.String("Node", node.Card.String()),
+			zap.String("Directory", directory),
+			zap.Error(err),
+		)
+		return
+	}
+	// A controller is only available in a cgroup if it is enabled by all its parents
+	enableCgroupControllers(filepath.Dir(root))
+	enableCgroupControllers(root)
+	enableCgroupControllers(directory)
+
+	// Remove the cgroups left by a previous execution of the worker
+	entryList, _ := os.ReadDir(directory)
+	for _, entry := range entryList {
+		if entry.IsDir() {
+			node.removeCgroup(filepath.Join(directory, entry.Name()))
+		}
+	}
+	node.cgroupDirectory = directory
+}
+
+// enableCgroupControllers enables the controllers of cgroupControllerList available in a cgroup for its children
+func enableCgroupControllers(directory string) {
+	content, err := os.ReadFile(filepath.Join(directory, "cgroup.controllers"))
+	if err != nil {
+		return
+	}
+	for _, availableController := range strings.Fields(string(content)) {
+		for _, controller := range cgroupControllerList {
+			if controller == availableController {
+				// It fails if the cgroup contains processes, the controller is then not available
+				_ = os.WriteFile(filepath.Join(directory, "cgroup.subtree_control"), []byte("+"+controller), 0644)
+			}
+		}
+	}
+}
+
+// createCgroup creates the cgroup of an attempt of a job and sets the limits of the job. It returns
+// nil if the jobs are executed without cgroups.
+func (node *WorkerNode) createCgroup(job *core.Job, attempt *core.JobAttempt) (*jobCgroup, error) {
+	if node.cgroupDirectory == "" {
+		return nil, nil
+	}
+	cgroup := &jobCgroup{
+		path:          filepath.Join(node.cgroupDirectory, fmt.Sprintf("job-%s-attempt-%d", job.GetReference(), attempt.Number)),
+		controllerMap: make(map[string]bool),
+	}
+	if err := os.Mkdir(cgroup.path, 0755); err != nil {
+		return nil, err
+	}
+	if content, err := cgroup.read("cgroup.controllers"); err == nil {
+		for _, controller := range strings.Fields(content) {
+			cgroup.controllerMap[controller] = true
+		}
+	}
+
+	var err error
+	if job.MemoryLimit > 0 && cgroup.hasController("memory") {
+		err = cgroup.write("memory.max", strconv.FormatUint(job.MemoryLimit, 10))
+		// The job can not exceed its limit with swap. The file is missing without swap accounting.
+		_ = cgroup.write("memory.swap.max", "0")
+	}
+	if err == nil && job.CPUQuota > 0 && cgroup.hasController("cpu") {
+		err = cgroup.write("cpu.max", fmt.Sprintf("%d %d", int64(job.CPUQuota*cpuQuotaPeriod), cpuQuotaPeriod))
+	}
+	if err != nil {
+		node.removeCgroup(cgroup.path)
+		return nil, err
+	}
+	return cgroup, nil
+}
+
+// removeCgroup kills the processes left in a cgroup and removes it
+func (node *WorkerNode) removeCgroup(path string) {
+	killCgroup(path)
+	// The cgroup can only be removed once its processes have exited
+	var err error
+	for i := 0; i < cgroupRemoveRetryCount; i++ {
+		if err = os.Remove(path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	logger.Error("Error while removing cgroup",
+		zap.String("Node", node.Card.String()),
+		zap.String("Cgroup", path),
+		zap.Error(err),
+	)
+}
+
+// killCgroup kills all the processes of a cgroup
+func killCgroup(path string) {
+	if err := os.WriteFile(filepath.Join(path, "cgroup.kill"), []byte("1"), 0644); err != nil {
+		// cgroup.kill is only available since Linux 5.14
+		content, _ := os.ReadFile(filepath.Join(path, "cgroup.procs"))
+		for _, field := range strings.Fields(string(content)) {
+			if pid, err := strconv.Atoi(field); err == nil {
+				if process, err := os.FindProcess(pid); err == nil {
+					_ = process.Kill()
+				}
+			}
+		}
+	}
+}
+
+// hasController checks if a controller is available in the cgroup. A nil cgroup has no controller.
+func (cgroup *jobCgroup) hasController(controller string) bool {
+	return cgroup != nil && cgroup.controllerMap[controller]
+}
+
+// read returns the content of a file of the cgroup
+func (cgroup *jobCgroup) read(name string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(cgroup.path, name))
+	return string(content), err
+}
+
+// write sets the content of a file of the cgroup
+func (cgroup *jobCgroup) write(name string, value string) error {
+	return os.WriteFile(filepath.Join(cgroup.path, name), []byte(value), 0644)
+}
+
+// readKeyValue returns the value of a key in a file of the cgroup made of `<key> <value>` lines
+func (cgroup *jobCgroup) readKeyValue(name string, key string) (uint64, bool) {
+	content, err := cgroup.read(name)
+	if err != nil {
+		return 0, false
+	}
+	for _, line := range strings.Split(content, "\n") {
+		fieldList := strings.Fields(line)
+		if len(fieldList) == 2 && fieldList[0] == key {
+			value, err := strconv.ParseUint(fieldList[1], 10, 64)
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
+
+// addProcess moves a process to the cgroup
+func (cgroup *jobCgroup) addProcess(pid int) error {
+	return cgroup.write("cgroup.procs", strconv.Itoa(pid))
+}
+
+// isOutOfMemory checks if a process of the cgroup has been killed because the job exceeded its memory limit
+func (cgroup *jobCgroup) isOutOfMemory() bool {
+	if !cgroup.hasController("memory") {
+		return false
+	}
+	oomKillCount, _ := cgroup.readKeyValue("memory.events", "oom_kill")
+	return oomKillCount > 0
+}
+
+// startCommand starts a command and moves it to the cgroup of the job. The command must wait until
+// the gate given as its file descriptor 3 is closed, so that the job is not executed outside of its cgroup.
+func startCommand(command *exec.Cmd, cgroup *jobCgroup) error {
+	if cgroup == nil {
+		return command.Start()
+	}
+	gateReader, gateWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer gateWriter.Close()
+	command.ExtraFiles = []*os.File{gateReader}
+	err = command.Start()
+	gateReader.Close()
+	if err != nil {
+		return err
+	}
+	if err := cgroup.addProcess(command.Process.Pid); err != nil {
+		_ = command.Process.Kill()
+		_ = command.Wait()
+		return fmt.Errorf("Error while moving the job to its cgroup: %v", err)
+	}
+	return nil
+}
+
+// killWhenDone kills all the processes of the cgroup when the job is stopped, since the processes
+// started by the job in the background would keep it running
+func (cgroup *jobCgroup) killWhenDone(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		killCgroup(cgroup.path)
+	}()
+}
+
+// getResourceUsage returns the resources used by a job which has exited. The CPU time and the peak of
+// memory are read from the cgroup of the job if available, or from the kernel accounting of its process.
+func getResourceUsage(state *os.ProcessState, cgroup *jobCgroup, wallTime time.Duration) core.ResourceUsage {
+	usage := core.ResourceUsage{
+		WallTime:   wallTime,
+		CPUTime:    state.UserTime() + state.SystemTime(),
+		PeakMemory: getPeakMemory(state),
+	}
+	if cgroup == nil {
+		return usage
+	}
+	if cpuUsage, ok := cgroup.readKeyValue("cpu.stat", "usage_usec"); ok {
+		usage.CPUTime = time.Duration(cpuUsage) * time.Microsecond
+		usage.Cgroup = true
+	}
+	// memory.peak is only available since Linux 5.19
+	if content, err := cgroup.read("memory.peak"); err == nil {
+		if peakMemory, err := strconv.ParseUint(strings.TrimSpace(content), 10, 64); err == nil {
+			usage.PeakMemory = peakMemory
+			usage.Cgroup = true
+		}
+	}
+	return usage
+}
@@ -0,0 +1,27 @@
+//go:build linux
+
+package worker
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// getPeakMemory returns the maximum resident set size in bytes of a process which has exited
+func getPeakMemory(state *os.ProcessState) uint64 {
+	if rusage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		// The maximum resident set size is in kilobytes on Linux
+		return uint64(rusage.Maxrss) * 1024
+	}
+	return 0
+}
+
+// getSignal returns the name of the signal which terminated a process, or an empty string if it exited
+func getSignal(state *os.ProcessState) string {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return unix.SignalName(status.Signal())
+	}
+	return ""
+}
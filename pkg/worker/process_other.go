@@ -0,0 +1,21 @@
+//go:build !linux
+
+package worker
+
+import (
+	"os"
+	"syscall"
+)
+
+// getPeakMemory returns 0 because the unit of the maximum resident set size depends on the system
+func getPeakMemory(state *os.ProcessState) uint64 {
+	return 0
+}
+
+// getSignal returns the description of the signal which terminated a process, or an empty string if it exited
+func getSignal(state *os.ProcessState) string {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal().String()
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+)
+
+// First argument of the program when it is executed as the sandbox helper of a worker
+const SANDBOX_HELPER_COMMAND = "__sandbox"
+
+// Exit code of the sandbox helper when the sandbox can not be set up
+const SANDBOX_ERROR_EXIT_CODE = 125
+
+/*************
+ ** Sandbox **
+ *************/
+
+// IsSandboxHelper checks if the program is executed as the sandbox helper of a worker
+func IsSandboxHelper() bool {
+	return len(os.Args) > 1 && os.Args[1] == SANDBOX_HELPER_COMMAND
+}
+
+// RunSandboxHelper sets up the sandbox from the arguments of the program, then replaces the program
+// with the command to execute in the sandbox. It never returns.
+func RunSandboxHelper() {
+	err := runSandboxHelper(os.Args[2:])
+	fmt.Fprintln(os.Stderr, "Error while setting up the sandbox:", err)
+	os.Exit(SANDBOX_ERROR_EXIT_CODE)
+}
+
+// isSandboxed checks if a worker builds and executes its jobs in a sandbox
+func isSandboxed(workerId uint32) bool {
+	for _, sandboxWorkerId := range core.Config.SandboxWorkerIdList {
+		if sandboxWorkerId == workerId {
+			return true
+		}
+	}
+	return false
+}
+
+// getHiddenDirectoryList returns the directories of the cluster which are hidden from the jobs
+// in the sandbox: the working directory (state and log files), the compile cache, the blob stores and
+// the workspaces
+func (node *WorkerNode) getHiddenDirectoryList() []string {
+	var hiddenDirectoryList []string
+	for _, directory := range []string{".", core.Config.CompileCacheDirectory, core.Config.BlobDirectory, filepath.Dir(node.workspaceDirectory)} {
+		if absoluteDirectory, err := filepath.Abs(directory); err == nil {
+			hiddenDirectoryList = append(hiddenDirectoryList, absoluteDirectory)
+		}
+	}
+	return hiddenDirectoryList
+}
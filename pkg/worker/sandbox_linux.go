@@ -0,0 +1,288 @@
+//go:build linux
+
+package worker
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Size of the private /tmp of the sandbox
+const sandboxTmpSize = "size=256m"
+
+// Mount flags which must be kept when a mount is remounted in a user namespace
+var lockedMountFlagMap = map[string]uintptr{
+	"nosuid":     unix.MS_NOSUID,
+	"nodev":      unix.MS_NODEV,
+	"noexec":     unix.MS_NOEXEC,
+	"noatime":    unix.MS_NOATIME,
+	"nodiratime": unix.MS_NODIRATIME,
+	"relatime":   unix.MS_RELATIME,
+}
+
+// Architectures of the seccomp filter
+var auditArchMap = map[string]uint32{
+	"386":     unix.AUDIT_ARCH_I386,
+	"amd64":   unix.AUDIT_ARCH_X86_64,
+	"arm":     unix.AUDIT_ARCH_ARM,
+	"arm64":   unix.AUDIT_ARCH_AARCH64,
+	"ppc64le": unix.AUDIT_ARCH_PPC64LE,
+	"riscv64": unix.AUDIT_ARCH_RISCV64,
+	"s390x":   unix.AUDIT_ARCH_S390X,
+}
+
+// System calls denied by the seccomp filter of the sandbox: they would let a job change the
+// isolation of the sandbox, inspect other processes or administrate the system
+var deniedSyscallList = []uint32{
+	unix.SYS_MOUNT, unix.SYS_UMOUNT2, unix.SYS_PIVOT_ROOT, unix.SYS_CHROOT,
+	unix.SYS_SETNS, unix.SYS_UNSHARE,
+	unix.SYS_PTRACE, unix.SYS_PROCESS_VM_READV, unix.SYS_PROCESS_VM_WRITEV,
+	unix.SYS_KEXEC_LOAD, unix.SYS_INIT_MODULE, unix.SYS_FINIT_MODULE, unix.SYS_DELETE_MODULE,
+	unix.SYS_REBOOT, unix.SYS_SWAPON, unix.SYS_SWAPOFF, unix.SYS_ACCT, unix.SYS_QUOTACTL,
+	unix.SYS_SETTIMEOFDAY, unix.SYS_CLOCK_SETTIME, unix.SYS_SETHOSTNAME, unix.SYS_SETDOMAINNAME,
+	unix.SYS_BPF, unix.SYS_PERF_EVENT_OPEN, unix.SYS_USERFAULTFD,
+	unix.SYS_KEYCTL, unix.SYS_ADD_KEY, unix.SYS_REQUEST_KEY,
+	unix.SYS_OPEN_BY_HANDLE_AT, unix.SYS_NAME_TO_HANDLE_AT,
+}
+
+// Return values of a seccomp filter
+const (
+	seccompRetKillProcess = 0x80000000
+	seccompRetErrno       = 0x00050000
+	seccompRetAllow       = 0x7fff0000
+)
+
+// sandboxCommand changes a command so that it is executed in a sandbox by the sandbox helper, in
+// new user, mount, PID, network, IPC and UTS namespaces. The directory of the command is its workspace.
+func sandboxCommand(command *exec.Cmd, hiddenDirectoryList []string) error {
+	argList := []string{"/proc/self/exe", SANDBOX_HELPER_COMMAND, "-workspace", command.Dir}
+	for _, directory := range hiddenDirectoryList {
+		argList = append(argList, "-hide", directory)
+	}
+	argList = append(argList, "--", command.Path)
+	argList = append(argList, command.Args[1:]...)
+
+	command.Path = "/proc/self/exe"
+	command.Args = argList
+	// The helper starts in the working directory of the cluster and moves to the workspace in the sandbox
+	command.Dir = ""
+	command.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: 0, HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+	return nil
+}
+
+// runSandboxHelper sets up the sandbox from the namespaces created for the helper, then executes
+// the command. It only returns if the sandbox can not be set up.
+func runSandboxHelper(argList []string) error {
+	flagSet := flag.NewFlagSet(SANDBOX_HELPER_COMMAND, flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	workspace := flagSet.String("workspace", "", "workspace of the job")
+	var hiddenDirectoryList []string
+	flagSet.Func("hide", "directory hidden from the job", func(directory string) error {
+		hiddenDirectoryList = append(hiddenDirectoryList, directory)
+		return nil
+	})
+	if err := flagSet.Parse(argList); err != nil {
+		return err
+	}
+	if *workspace == "" || flagSet.NArg() == 0 {
+		return fmt.Errorf("Missing workspace or command")
+	}
+	commandPath, err := exec.LookPath(flagSet.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// The seccomp filter only applies to the thread which executes the command
+	runtime.LockOSThread()
+	if err := setUpSandboxMounts(*workspace, hiddenDirectoryList); err != nil {
+		return err
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return err
+	}
+	if err := os.Chdir(*workspace); err != nil {
+		return err
+	}
+	if err := setUpSeccompFilter(); err != nil {
+		return err
+	}
+	return syscall.Exec(commandPath, flagSet.Args(), os.Environ())
+}
+
+// setUpSandboxMounts makes the root read-only, hides the directories of the cluster, gives the job
+// a private /tmp and a /proc of its PID namespace, and keeps its workspace writable
+func setUpSandboxMounts(workspace string, hiddenDirectoryList []string) error {
+	// The mounts of the sandbox must not be propagated to the host
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("Private mounts: %v", err)
+	}
+
+	// The mounts of the root are copied and the copy becomes the root, so that files opened for
+	// writing by the helper do not prevent making the mounts read-only
+	newRoot := os.TempDir()
+	if err := unix.Mount("/", newRoot, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("Copy root: %v", err)
+	}
+	if err := os.Chdir(newRoot); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("Change root: %v", err)
+	}
+	if err := unix.Unmount(".", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("Unmount old root: %v", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := remountAllReadOnly(); err != nil {
+		return err
+	}
+
+	// Keep a reference to the workspace, which may be hidden below
+	workspaceFd, err := unix.Open(workspace, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("Open workspace: %v", err)
+	}
+	defer unix.Close(workspaceFd)
+
+	// Parent directories are hidden first, their subdirectories do not exist anymore
+	hiddenDirectoryList = append(hiddenDirectoryList, os.TempDir())
+	sort.Strings(hiddenDirectoryList)
+	for _, directory := range hiddenDirectoryList {
+		if info, err := os.Stat(directory); err != nil || !info.IsDir() || directory == "/" {
+			continue
+		}
+		if err := unix.Mount("tmpfs", directory, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, sandboxTmpSize); err != nil {
+			return fmt.Errorf("Hide %s: %v", directory, err)
+		}
+	}
+
+	if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("Mount /proc: %v", err)
+	}
+
+	// The workspace may be in a hidden directory, so it is mounted again at the same path
+	if err := os.MkdirAll(workspace, 0755); err != nil {
+		return fmt.Errorf("Create workspace: %v", err)
+	}
+	workspaceSource := fmt.Sprintf("/proc/self/fd/%d", workspaceFd)
+	if err := unix.Mount(workspaceSource, workspace, "", unix.MS_BIND, ""); err != nil {
+		return fmt.Errorf("Mount workspace: %v", err)
+	}
+	// The mount of the workspace is read-only like the mount it comes from
+	if err := unix.Mount("", workspace, "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("Writable workspace: %v", err)
+	}
+	return nil
+}
+
+// remountAllReadOnly makes all the mounts read-only, keeping their locked flags
+func remountAllReadOnly() error {
+	mountInfo, err := os.ReadFile("/proc/self/mountinfo")
+	if err != nil {
+		return fmt.Errorf("Read mounts: %v", err)
+	}
+	for _, line := range strings.Split(string(mountInfo), "\n") {
+		// Fields: mount id, parent id, device, root, mount point, mount options, ...
+		fieldList := strings.Fields(line)
+		if len(fieldList) < 6 {
+			continue
+		}
+		mountPoint := unescapeMountPoint(fieldList[4])
+		flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+		for _, option := range strings.Split(fieldList[5], ",") {
+			flags |= lockedMountFlagMap[option]
+		}
+		if err := unix.Mount("", mountPoint, "", flags, ""); err != nil {
+			// A mount point hidden by another mount can not be reached
+			if err == unix.ENOENT {
+				continue
+			}
+			return fmt.Errorf("Read-only %s: %v", mountPoint, err)
+		}
+	}
+	return nil
+}
+
+// unescapeMountPoint decodes the octal escapes of the spaces, tabs, newlines and backslashes of a mount point
+func unescapeMountPoint(mountPoint string) string {
+	var builder strings.Builder
+	for i := 0; i < len(mountPoint); i++ {
+		if mountPoint[i] == '\\' && i+3 < len(mountPoint) {
+			if value, err := strconv.ParseUint(mountPoint[i+1:i+4], 8, 8); err == nil {
+				builder.WriteByte(byte(value))
+				i += 3
+				continue
+			}
+		}
+		builder.WriteByte(mountPoint[i])
+	}
+	return builder.String()
+}
+
+// setUpSeccompFilter denies the system calls of deniedSyscallList with EPERM. The process is killed
+// if it uses a system call of another architecture.
+func setUpSeccompFilter() error {
+	auditArch, ok := auditArchMap[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("Seccomp is not supported on %s", runtime.GOARCH)
+	}
+
+	statement := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jumpTrue uint8, jumpFalse uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jumpTrue, Jf: jumpFalse, K: k}
+	}
+	// Offsets of the architecture and of the system call number in struct seccomp_data
+	const archOffset, nrOffset = 4, 0
+
+	filter := []unix.SockFilter{
+		statement(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, archOffset),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, auditArch, 1, 0),
+		statement(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		statement(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, nrOffset),
+	}
+	if runtime.GOARCH == "amd64" {
+		// Deny the x32 system calls, which have other numbers
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, 0x40000000, 0, 1),
+			statement(unix.BPF_RET|unix.BPF_K, seccompRetKillProcess),
+		)
+	}
+	for _, syscallNumber := range deniedSyscallList {
+		filter = append(filter,
+			jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, syscallNumber, 0, 1),
+			statement(unix.BPF_RET|unix.BPF_K, seccompRetErrno|uint32(unix.EPERM)),
+		)
+	}
+	filter = append(filter, statement(unix.BPF_RET|unix.BPF_K, seccompRetAllow))
+
+	program := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("No new privileges: %v", err)
+	}
+	if err := unix.Prctl(unix.PR_SET_SECCOMP, unix.SECCOMP_MODE_FILTER, uintptr(unsafe.Pointer(&program)), 0, 0); err != nil {
+		return fmt.Errorf("Seccomp filter: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package worker
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// sandboxCommand fails because the sandbox relies on Linux namespaces and seccomp
+func sandboxCommand(command *exec.Cmd, hiddenDirectoryList []string) error {
+	return fmt.Errorf("The sandbox is only supported on Linux")
+}
+
+// runSandboxHelper fails because the sandbox relies on Linux namespaces and seccomp
+func runSandboxHelper(argList []string) error {
+	return fmt.Errorf("The sandbox is only supported on Linux")
+}
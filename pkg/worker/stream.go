@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+// Maximum size in bytes of the end of each output sent to a subscriber when it starts following a job
+const outputReplaySize = 16 << 10
+
+/*******************
+ ** Output Stream **
+ *******************/
+
+// outputSubscriber is a node following the outputs of the running job
+type outputSubscriber struct {
+	chunkChannel chan core.OutputChunk
+	// Number of bytes not sent yet because the channel was full
+	skippedSize uint64
+}
+
+// outputStream records the outputs of an attempt of a job and sends them to its subscribers as they
+// are written. A slow subscriber never blocks the job: the chunks it can not receive are skipped.
+type outputStream struct {
+	mutex          sync.Mutex
+	attempt        uint32
+	stdout         bytes.Buffer
+	stderr         bytes.Buffer
+	subscriberList []*outputSubscriber
+	closed         bool
+}
+
+// outputStreamWriter writes to the standard output or error of an output stream
+type outputStreamWriter struct {
+	stream *outputStream
+	stderr bool
+}
+
+// newOutputStream creates the output stream of an attempt of a job
+func newOutputStream(attempt uint32) *outputStream {
+	return &outputStream{attempt: attempt}
+}
+
+// Write records the data and sends it to the subscribers
+func (writer outputStreamWriter) Write(data []byte) (int, error) {
+	stream := writer.stream
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if writer.stderr {
+		stream.stderr.Write(data)
+	} else {
+		stream.stdout.Write(data)
+	}
+	for _, subscriber := range stream.subscriberList {
+		// The chunk owns a copy since the caller may reuse the data
+		subscriber.send(core.OutputChunk{Attempt: stream.attempt, Stderr: writer.stderr, Data: append([]byte{}, data...)})
+	}
+	return len(data), nil
+}
+
+// stdoutWriter returns the writer of the standard output of the job
+func (stream *outputStream) stdoutWriter() outputStreamWriter {
+	return outputStreamWriter{stream: stream, stderr: false}
+}
+
+// stderrWriter returns the writer of the standard error of the job
+func (stream *outputStream) stderrWriter() outputStreamWriter {
+	return outputStreamWriter{stream: stream, stderr: true}
+}
+
+// subscribe sends the end of the outputs already written to a new subscriber, then the next chunks
+func (stream *outputStream) subscribe(chunkChannel chan core.OutputChunk) {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	if stream.closed {
+		close(chunkChannel)
+		return
+	}
+	subscriber := &outputSubscriber{chunkChannel: chunkChannel}
+	subscriber.send(core.OutputChunk{Attempt: stream.attempt})
+	for _, output := range []struct {
+		buffer *bytes.Buffer
+		stderr bool
+	}{{&stream.stdout, false}, {&stream.stderr, true}} {
+		data := output.buffer.Bytes()
+		if len(data) > outputReplaySize {
+			// The replay starts at the beginning of a line when possible
+			start := len(data) - outputReplaySize
+			if index := bytes.IndexByte(data[start:], '\n'); index >= 0 && start+index+1 < len(data) {
+				start += index + 1
+			}
+			subscriber.skippedSize += uint64(start)
+			data = data[start:]
+		}
+		if len(data) > 0 {
+			subscriber.send(core.OutputChunk{Attempt: stream.attempt, Stderr: output.stderr, Data: append([]byte{}, data...)})
+		}
+	}
+	stream.subscriberList = append(stream.subscriberList, subscriber)
+}
+
+// close ends the stream of all the subscribers once the attempt has ended
+func (stream *outputStream) close() {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+	stream.closed = true
+	for _, subscriber := range stream.subscriberList {
+		if subscriber.skippedSize > 0 {
+			subscriber.send(core.OutputChunk{Attempt: stream.attempt})
+		}
+		close(subscriber.chunkChannel)
+	}
+	stream.subscriberList = nil
+}
+
+// send sends a chunk to the subscriber without blocking. The size of the skipped chunks is given
+// with the next chunk which can be sent.
+func (subscriber *outputSubscriber) send(chunk core.OutputChunk) {
+	chunk.SkippedSize = subscriber.skippedSize
+	select {
+	case subscriber.chunkChannel <- chunk:
+		subscriber.skippedSize = 0
+	default:
+		subscriber.skippedSize += uint64(len(chunk.Data))
+	}
+}
+
+// listenOutputSubscriptions adds the subscribers of the running job to its output stream. The
+// stream of a subscriber is closed immediately if the job is not running on the worker.
+func (node *WorkerNode) listenOutputSubscriptions() {
+	for subscription := range node.Channel.SubscribeOutput {
+		node.runningJobMutex.Lock()
+		if node.runningJobReference == subscription.JobReference && node.runningOutput != nil {
+			logger.Debug("Output of job followed",
+				zap.String("Node", node.Card.String()),
+				zap.String("Job", subscription.JobReference),
+				zap.String("Subscriber", subscription.FromNode.String()),
+			)
+			node.runningOutput.subscribe(subscription.Chunks)
+		} else {
+			close(subscription.Chunks)
+		}
+		node.runningJobMutex.Unlock()
+	}
+}
@@ -2,9 +2,13 @@ package worker
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Timelessprod/algorep/pkg/core"
@@ -23,6 +27,26 @@ type WorkerNode struct {
 	LastLeaderId uint32
 
 	Channel core.ChannelContainer
+
+	// Reference of the job being executed, function to stop it and outputs of the attempt
+	runningJobMutex     sync.Mutex
+	runningJobReference string
+	stopRunningJob      context.CancelFunc
+	runningOutput       *outputStream
+	// Why the job being executed has been stopped, if it has
+	runningJobCancelled bool
+	runningJobPreempted bool
+
+	// Binaries already built by the worker, nil if the cache is disabled
+	compileCache *compileCache
+	// Directory containing the workspaces of the jobs
+	workspaceDirectory string
+	// Build and execute the jobs in a sandbox
+	sandboxed bool
+	// cgroup containing the cgroups of the jobs, empty if the jobs are executed without cgroups
+	cgroupDirectory string
+	// Large inputs and outputs of the jobs, nil if the blob stores are disabled
+	blobStore *core.BlobStore
 }
 
 // Init initializes the worker node
@@ -31,7 +55,17 @@ func (node *WorkerNode) Init(id uint32) {
 	node.Card = core.NodeCard{Id: id, Type: core.WorkerNodeType}
 	node.Channel = core.ChannelContainer{
 		ResponseCommand: make(chan core.ResponseCommandRPC, core.Config.ChannelBufferSize),
-		JobQueue:        make(chan core.Job, core.Config.ChannelBufferSize),
+		JobQueue:        core.NewJobQueue(),
+		CancelJob:       make(chan string, core.Config.ChannelBufferSize),
+		SubscribeOutput: make(chan core.OutputSubscription, core.Config.ChannelBufferSize),
+	}
+	node.compileCache = newCompileCache(id)
+	node.initWorkspaceDirectory()
+	node.sandboxed = isSandboxed(id)
+	node.initCgroupDirectory()
+	node.blobStore = core.NewBlobStore(node.Card)
+	if node.blobStore != nil {
+		node.Channel.RequestBlob = make(chan core.BlobRequest, core.Config.ChannelBufferSize)
 	}
 	node.LastLeaderId = 0 // Valeur par défaut le temps de trouver le leader
 }
@@ -39,8 +73,13 @@ func (node *WorkerNode) Init(id uint32) {
 // Run the worker node
 func (node *WorkerNode) Run() {
 	logger.Info("Node started", zap.String("Node", node.Card.String()))
+	go node.listenCancelJob()
+	go node.listenOutputSubscriptions()
+	if node.blobStore != nil {
+		go node.blobStore.Serve(node.Channel.RequestBlob)
+	}
 	for {
-		job := <-node.Channel.JobQueue
+		job := node.Channel.JobQueue.Pop()
 		node.processJob(job)
 	}
 }
@@ -50,96 +89,332 @@ func (node *WorkerNode) processJob(job core.Job) {
 	logger.Info("Processing job",
 		zap.String("Node", node.Card.String()),
 		zap.String("Job", job.GetReference()),
+		zap.String("Priority", job.Priority.String()),
 	)
 	logger.Debug("Job Details",
 		zap.String("Node", node.Card.String()),
 		zap.String("Job", job.GetReference()),
 		zap.String("Input", job.Input),
-		zap.String("Output", job.Output),
 	)
 
-	// Execute the job
-	node.ExecuteJob(&job)
+	// Execute the job and record the attempt
+	attempt := core.JobAttempt{
+		Number:    uint32(len(job.Attempts)) + 1,
+		WorkerId:  int(node.Id),
+		StartedAt: time.Now(),
+		ExitCode:  core.NO_EXIT_CODE,
+	}
+	queuedJob := job
+	ctx := node.startRunningJob(job.GetReference(), attempt.Number)
+	if core.Config.JobPreemption && job.PreemptionCount < core.Config.MaxJobPreemptions {
+		go node.preemptRunningJob(ctx, job)
+	}
+	err := node.ExecuteJob(ctx, &job, &attempt)
+	requeued, cancelled := node.endRunningJob(queuedJob, err)
+	if requeued {
+		return
+	}
+	if err != nil && cancelled {
+		err = fmt.Errorf("job cancelled")
+	}
+	attempt.FinishedAt = time.Now()
+	if err != nil {
+		attempt.Error = err.Error()
+		job.State = core.JobFailed
+	} else {
+		job.State = core.JobSucceeded
+	}
+	job.Attempts = append(job.Attempts, attempt)
+
+	// Only the references of the large inputs and outputs are sent to the leader
+	allBlobFieldList := append(append([]core.BlobField{}, core.JobInputBlobFieldList...), core.JobResultBlobFieldList...)
+	if err := job.StoreBlobs(node.blobStore, allBlobFieldList); err != nil {
+		logger.Error("Error while storing blobs of job",
+			zap.String("Node", node.Card.String()),
+			zap.String("Job", job.GetReference()),
+			zap.Error(err),
+		)
+	}
 
 	// Close the job
-	job.State = core.JobDone
 	node.closeJob(job)
 }
 
-// ExecuteJob executes a job
-func (node *WorkerNode) ExecuteJob(job *core.Job) {
-	logger.Info("Execute job",
+// startRunningJob registers the job being executed and returns the context used to stop it
+func (node *WorkerNode) startRunningJob(reference string, attempt uint32) context.Context {
+	node.runningJobMutex.Lock()
+	defer node.runningJobMutex.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	node.runningJobReference = reference
+	node.stopRunningJob = cancel
+	node.runningOutput = newOutputStream(attempt)
+	node.runningJobCancelled = false
+	node.runningJobPreempted = false
+	return ctx
+}
+
+// endRunningJob unregisters the job being executed and ends the stream of its outputs. If its
+// execution has failed after it has been preempted, and it has not been cancelled, the job is put
+// back in the queue as it was before its execution: it returns true. A job which has succeeded before
+// being preempted keeps its result. It also returns whether the job has been cancelled.
+func (node *WorkerNode) endRunningJob(queuedJob core.Job, err error) (bool, bool) {
+	node.runningJobMutex.Lock()
+	defer node.runningJobMutex.Unlock()
+	node.stopRunningJob()
+	node.runningOutput.close()
+	requeued := err != nil && node.runningJobPreempted && !node.runningJobCancelled
+	if requeued {
+		queuedJob.PreemptionCount++
+		node.Channel.JobQueue.PushFront(queuedJob)
+	}
+	node.runningJobReference = ""
+	node.stopRunningJob = nil
+	node.runningOutput = nil
+	return requeued, node.runningJobCancelled
+}
+
+// preemptRunningJob stops the running job when a job with a higher priority is queued, until the
+// running job has ended. The stop is ignored once the running job has been unregistered.
+func (node *WorkerNode) preemptRunningJob(ctx context.Context, job core.Job) {
+	if !node.Channel.JobQueue.WaitForHigherPriority(ctx, job.Priority) {
+		return
+	}
+	node.runningJobMutex.Lock()
+	defer node.runningJobMutex.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+	logger.Info("Preempt running job",
 		zap.String("Node", node.Card.String()),
 		zap.String("Job", job.GetReference()),
+		zap.String("Priority", job.Priority.String()),
 	)
+	node.runningJobPreempted = true
+	node.stopRunningJob()
+}
 
-	reference := job.GetReference()
-	binaryName := fmt.Sprintf("job-%s.out", reference)
+// listenCancelJob stops the running job when the leader cancels it. A job which is not running
+// anymore may have been preempted and put back in the queue: it is removed from the queue.
+func (node *WorkerNode) listenCancelJob() {
+	for reference := range node.Channel.CancelJob {
+		node.runningJobMutex.Lock()
+		if node.runningJobReference == reference {
+			logger.Info("Stop cancelled job",
+				zap.String("Node", node.Card.String()),
+				zap.String("Job", reference),
+			)
+			node.runningJobCancelled = true
+			node.stopRunningJob()
+		} else {
+			node.Channel.JobQueue.Remove(reference)
+		}
+		node.runningJobMutex.Unlock()
+	}
+}
 
-	// Compile the job
-	logger.Debug("Compiling job ...",
+// ExecuteJob executes a job and returns an error if the job can not be compiled, exits with an error
+// or exceeds its timeout. The exit code and the timeout are reported in the attempt, and the exit
+// code, the signal, the compile log, the outputs and the artifacts in the job. The job is stopped
+// when the context is cancelled.
+func (node *WorkerNode) ExecuteJob(ctx context.Context, job *core.Job, attempt *core.JobAttempt) error {
+	logger.Info("Execute job",
 		zap.String("Node", node.Card.String()),
 		zap.String("Job", job.GetReference()),
-		zap.String("BinaryName", binaryName),
+		zap.Uint32("Attempt", attempt.Number),
 	)
-	compileCommand := exec.Command("g++", "-o", binaryName, "-x", "c++", "-")
-	compileCommand.Stdin = strings.NewReader(job.Input)
-	var stdoutCompile, stderrCompile bytes.Buffer
-	compileCommand.Stdout = &stdoutCompile
-	compileCommand.Stderr = &stderrCompile
-	err := compileCommand.Run()
+	job.ResetResult()
+
+	// Fetch the large inputs of the job from the node which stored them
+	if err := job.LoadBlobs(node.blobStore, core.JobInputBlobFieldList); err != nil {
+		return err
+	}
+
+	// Build and run the job in its own workspace, with the files of a multi-file job
+	workspace, err := node.createWorkspace(job, attempt)
 	if err != nil {
-		logger.Error("Error while compiling job",
+		return err
+	}
+	defer node.removeWorkspace(job, workspace)
+	if core.Config.KeepWorkspaces {
+		attempt.Workspace = workspace
+	}
+	if job.Archive != nil {
+		if err := core.ExtractArchive(job.Archive, workspace); err != nil {
+			return err
+		}
+	}
+	binaryName := filepath.Join(workspace, job.GetExecutable())
+
+	// Compile the job, unless its binary is in the compile cache
+	if err := job.CheckCompilerOptions(); err != nil {
+		return err
+	}
+	cacheKey := getCompileCacheKey(job)
+	if node.compileCache != nil && node.compileCache.Get(cacheKey, binaryName) {
+		logger.Debug("Binary found in compile cache",
 			zap.String("Node", node.Card.String()),
 			zap.String("Job", job.GetReference()),
-			zap.String("Error", err.Error()),
+			zap.String("Key", cacheKey),
 		)
-		errorPrompt := "--- Error while compiling job ---\n%s--- StdOut ---\n%s---StdError---%s"
-		job.Output = fmt.Sprintf(errorPrompt, err.Error(), stdoutCompile.String(), stderrCompile.String())
-		return
+		attempt.CompileCacheHit = true
+	} else {
+		if err := node.compileJob(ctx, job, binaryName, workspace); err != nil {
+			return err
+		}
+		if node.compileCache != nil {
+			node.compileCache.Put(cacheKey, binaryName)
+		}
 	}
 
-	// Run the binary
+	// Run the binary in the cgroup of the job
+	cgroup, err := node.createCgroup(job, attempt)
+	if err != nil {
+		return err
+	}
+	if cgroup != nil {
+		defer node.removeCgroup(cgroup.path)
+	}
+	if job.CPUQuota > 0 && !cgroup.hasController("cpu") {
+		logger.Warn("The CPU quota of the job is not enforced without the cpu controller of cgroup v2",
+			zap.String("Node", node.Card.String()),
+			zap.String("Job", job.GetReference()),
+		)
+	}
 	logger.Debug("Running job ...",
 		zap.String("Node", node.Card.String()),
 		zap.String("Job", job.GetReference()),
 		zap.String("BinaryName", binaryName),
 	)
-	runCommandString := fmt.Sprintf("./%s", binaryName)
-	runCommand := exec.Command(runCommandString)
-	var stdoutRun, stderrRun bytes.Buffer
-	runCommand.Stdout = &stdoutRun
-	runCommand.Stderr = &stderrRun
-	err = runCommand.Run()
-	if err != nil {
+	runCtx, cancel := context.WithCancel(ctx)
+	if job.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, job.Timeout)
+	}
+	defer cancel()
+	runCommand := getRunCommand(runCtx, binaryName, job, cgroup)
+	runCommand.Dir = workspace
+	runCommand.Env = append(os.Environ(), job.GetEnvList()...)
+	runCommand.Stdin = strings.NewReader(job.Stdin)
+	if node.sandboxed {
+		if err := sandboxCommand(runCommand, node.getHiddenDirectoryList()); err != nil {
+			return err
+		}
+	}
+	// The outputs are streamed to the nodes following the job while it runs
+	output := node.runningOutput
+	if output == nil {
+		output = newOutputStream(attempt.Number)
+	}
+	runCommand.Stdout = output.stdoutWriter()
+	runCommand.Stderr = output.stderrWriter()
+	startedAt := time.Now()
+	runErr := startCommand(runCommand, cgroup)
+	if runErr == nil {
+		if cgroup != nil {
+			cgroup.killWhenDone(runCtx)
+		}
+		runErr = runCommand.Wait()
+	}
+	if runCommand.ProcessState != nil {
+		attempt.ExitCode = runCommand.ProcessState.ExitCode()
+		job.ExitCode = attempt.ExitCode
+		job.Signal = getSignal(runCommand.ProcessState)
+		attempt.Signal = job.Signal
+		attempt.Usage = getResourceUsage(runCommand.ProcessState, cgroup, time.Since(startedAt))
+	}
+	if runCtx.Err() == context.DeadlineExceeded {
+		attempt.TimedOut = true
+		runErr = fmt.Errorf("job exceeded its timeout of %v", job.Timeout)
+	} else if cgroup.isOutOfMemory() {
+		runErr = fmt.Errorf("job exceeded its memory limit of %s", core.FormatMemorySize(job.MemoryLimit))
+	}
+	if runErr != nil {
 		logger.Error("Error while running job",
 			zap.String("Node", node.Card.String()),
 			zap.String("Job", job.GetReference()),
-			zap.String("Error", err.Error()),
+			zap.String("Error", runErr.Error()),
 		)
 	}
-	job.Output = fmt.Sprint(stdoutRun.String(), stderrRun.String())
+	output.mutex.Lock()
+	job.Stdout = output.stdout.String()
+	job.Stderr = output.stderr.String()
+	output.mutex.Unlock()
+
+	// The artifacts are collected even if the job failed, since they may help to understand why
+	if err := collectArtifacts(job, workspace); err != nil {
+		logger.Error("Error while collecting artifacts of job",
+			zap.String("Node", node.Card.String()),
+			zap.String("Job", job.GetReference()),
+			zap.Error(err),
+		)
+		if runErr == nil {
+			runErr = err
+		}
+	}
 	logger.Debug("Job has been executed",
 		zap.String("Node", node.Card.String()),
 		zap.String("Job", job.GetReference()),
-		zap.String("Output", job.Output),
+		zap.Int("ExitCode", job.ExitCode),
+		zap.String("Signal", job.Signal),
 	)
+	return runErr
+}
 
-	// Remove the binary
-	logger.Debug("Removing binary ...",
+// compileJob compiles a job into its binary. The output of the compiler is the output of the job if it fails.
+func (node *WorkerNode) compileJob(ctx context.Context, job *core.Job, binaryName string, workspace string) error {
+	logger.Debug("Compiling job ...",
 		zap.String("Node", node.Card.String()),
 		zap.String("Job", job.GetReference()),
 		zap.String("BinaryName", binaryName),
 	)
-	removeComand := exec.Command("rm", "-f", binaryName)
-	err = removeComand.Run()
+	compileCommand, err := getCompileCommand(ctx, job, binaryName, workspace)
 	if err != nil {
-		logger.Error("Error while removing binary",
+		return err
+	}
+	if node.sandboxed {
+		if err := sandboxCommand(compileCommand, node.getHiddenDirectoryList()); err != nil {
+			return err
+		}
+	}
+	var compileLog bytes.Buffer
+	compileCommand.Stdout = &compileLog
+	compileCommand.Stderr = &compileLog
+	err = compileCommand.Run()
+	job.CompileLog = compileLog.String()
+	if err != nil {
+		logger.Error("Error while compiling job",
 			zap.String("Node", node.Card.String()),
 			zap.String("Job", job.GetReference()),
 			zap.String("Error", err.Error()),
 		)
+		return fmt.Errorf("compilation failed: %v", err)
+	}
+	return nil
+}
+
+// getRunCommand returns the command executing the binary of a job. The CPU and memory limits of
+// the job are set with ulimit by a shell which then replaces itself with the binary. The memory
+// limit is enforced by the cgroup of the job instead if available, and the shell waits until it
+// has been moved to the cgroup.
+func getRunCommand(ctx context.Context, binaryPath string, job *core.Job, cgroup *jobCgroup) *exec.Cmd {
+	var limitList []string
+	if cgroup != nil {
+		limitList = append(limitList, "read _ <&3; exec 3<&-")
+	}
+	if job.CPULimit > 0 {
+		seconds := (job.CPULimit + time.Second - 1) / time.Second
+		limitList = append(limitList, fmt.Sprintf("ulimit -t %d", seconds))
+	}
+	if job.MemoryLimit > 0 && !cgroup.hasController("memory") {
+		kilobytes := (job.MemoryLimit + 1023) / 1024
+		limitList = append(limitList, fmt.Sprintf("ulimit -v %d", kilobytes))
 	}
+	if len(limitList) == 0 {
+		return exec.CommandContext(ctx, binaryPath, job.Args...)
+	}
+
+	script := strings.Join(append(limitList, `exec "$0" "$@"`), " && ")
+	shellArgs := append([]string{"-c", script, binaryPath}, job.Args...)
+	return exec.CommandContext(ctx, "/bin/sh", shellArgs...)
 }
 
 // closeJob closes a job
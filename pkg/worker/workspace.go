@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Timelessprod/algorep/pkg/core"
+	"go.uber.org/zap"
+)
+
+/***************
+ ** Workspace **
+ ***************/
+
+// initWorkspaceDirectory creates the directory containing the workspaces of the jobs executed by
+// the worker. The workspaces left by a previous execution of the worker are removed, unless the
+// workspaces are kept for debugging.
+func (node *WorkerNode) initWorkspaceDirectory() {
+	root := core.Config.WorkspaceDirectory
+	if root == "" {
+		root = os.TempDir()
+	}
+	directory, err := filepath.Abs(filepath.Join(root, fmt.Sprintf("algorep-worker-%d", node.Id)))
+	if err != nil {
+		directory = filepath.Join(root, fmt.Sprintf("algorep-worker-%d", node.Id))
+	}
+	if !core.Config.KeepWorkspaces {
+		if err := os.RemoveAll(directory); err != nil {
+			logger.Error("Error while removing old workspaces",
+				zap.String("Node", node.Card.String()),
+				zap.String("Directory", directory),
+				zap.Error(err),
+			)
+		}
+	}
+	node.workspaceDirectory = directory
+}
+
+// createWorkspace creates an empty workspace in which an attempt of a job is built and executed
+func (node *WorkerNode) createWorkspace(job *core.Job, attempt *core.JobAttempt) (string, error) {
+	if err := os.MkdirAll(node.workspaceDirectory, os.ModePerm); err != nil {
+		return "", err
+	}
+	pattern := fmt.Sprintf("job-%s-attempt-%d-", job.GetReference(), attempt.Number)
+	return os.MkdirTemp(node.workspaceDirectory, pattern)
+}
+
+// removeWorkspace removes the workspace of a job after its execution, unless the workspaces are
+// kept for debugging
+func (node *WorkerNode) removeWorkspace(job *core.Job, workspace string) {
+	if core.Config.KeepWorkspaces {
+		logger.Info("Keep workspace",
+			zap.String("Node", node.Card.String()),
+			zap.String("Job", job.GetReference()),
+			zap.String("Workspace", workspace),
+		)
+		return
+	}
+	logger.Debug("Removing workspace ...",
+		zap.String("Node", node.Card.String()),
+		zap.String("Job", job.GetReference()),
+		zap.String("Workspace", workspace),
+	)
+	if err := os.RemoveAll(workspace); err != nil {
+		logger.Error("Error while removing workspace",
+			zap.String("Node", node.Card.String()),
+			zap.String("Job", job.GetReference()),
+			zap.String("Workspace", workspace),
+			zap.Error(err),
+		)
+	}
+}